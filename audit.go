@@ -0,0 +1,151 @@
+package glogger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/MSLibs/glogger/core/sink"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Keys of the audit entry schema. The request ID and source IP use the
+// RequestID and SourceIP keys.
+const (
+	Action  string = "action"
+	Target  string = "target"
+	Actor   string = "actor"
+	Outcome string = "outcome"
+)
+
+// auditLog is the audit channel of one logger. It has its own output and
+// bypasses the level, sampling, rate limiting, dedup and async settings of
+// the application log.
+type auditLog struct {
+	core   zapcore.Core
+	out    *sink.DurableWriter
+	errOut zapcore.WriteSyncer
+}
+
+var (
+	auditMu  sync.RWMutex
+	auditStd *auditLog
+)
+
+// ErrNoAudit is returned by Audit when no audit channel is configured. Audit
+// entries are never written to the application log, whose filters could drop
+// them.
+var ErrNoAudit = errors.New("glogger: no audit channel configured")
+
+func (s *sinkSet) buildAudit(gconfig GLoggerConfig, ac AuditConfig) error {
+	if ac.Path == "" {
+		ac.Path = "./logs/audit.log"
+	}
+	encoding := ac.Encoding
+	if encoding == "" {
+		encoding = "json"
+	}
+	enc, err := newEncoder(gconfig, encoding)
+	if err != nil {
+		return err
+	}
+	ws, err := s.openPath(ac.Path)
+	if err != nil {
+		return err
+	}
+	if ac.Chain != nil {
+		if ws, err = chain(ac.Path, encoding, *ac.Chain, ws); err != nil {
+			return err
+		}
+	}
+	out := sink.NewDurableWriter(ws, ac.SyncEvery, ac.SyncInterval)
+	all := zap.LevelEnablerFunc(func(zapcore.Level) bool { return true })
+	s.audit = &auditLog{
+		core:   zapcore.NewCore(enc, out, all),
+		out:    out,
+		errOut: s.errOut,
+	}
+	return nil
+}
+
+// write writes an entry straight to the audit core, so that a failed write
+// or sync reaches the caller. skip is the number of frames above write to
+// report as the caller.
+func (a *auditLog) write(skip int, msg string, fields []zap.Field) error {
+	ent := zapcore.Entry{
+		LoggerName: "audit",
+		Time:       time.Now(),
+		Level:      zapcore.InfoLevel,
+		Message:    msg,
+		Caller:     zapcore.NewEntryCaller(runtime.Caller(skip + 1)),
+	}
+	err := a.core.Write(ent, fields)
+	if err != nil {
+		fmt.Fprintf(a.errOut, "%v audit write error: %v\n", ent.Time, err)
+		a.errOut.Sync()
+	}
+	return err
+}
+
+// Audit records that the actor of ctx performed action on target, with the
+// default logger's audit channel.
+func Audit(ctx *context.Context, action, target string, fields ...zap.Field) error {
+	return std.Audit(ctx, action, target, fields...)
+}
+
+// Audit writes an audit entry carrying the actor (UserFlag), source IP and
+// request ID of ctx. The outcome is "success" unless fields include an
+// Outcome field. Loggers created without an audit channel use the one of the
+// most recent logger that has one, and otherwise return ErrNoAudit. A failed
+// write or sync of the audit output is returned as well.
+func (log GLogger) Audit(ctx *context.Context, action, target string, fields ...zap.Field) error {
+	audit := log.auditLog()
+	if audit == nil {
+		return ErrNoAudit
+	}
+	if ctx == nil {
+		ctx = log.context
+	}
+	payload := LogPayload{}
+	if ctx != nil {
+		payload = read(*ctx)
+	}
+	entry := []zap.Field{
+		zap.String(Action, action),
+		zap.String(Target, target),
+		zap.String(Actor, payload.UserFlag),
+		zap.String(SourceIP, payload.SourceIP),
+		zap.String(RequestID, payload.RequestID),
+	}
+	outcome := true
+	for _, f := range fields {
+		if f.Key == Outcome {
+			outcome = false
+		}
+	}
+	if outcome {
+		entry = append(entry, zap.String(Outcome, "success"))
+	}
+	entry = append(entry, fields...)
+	return audit.write(1, action, entry)
+}
+
+func (log GLogger) auditLog() *auditLog {
+	if log.sinks != nil && log.sinks.audit != nil {
+		return log.sinks.audit
+	}
+	auditMu.RLock()
+	defer auditMu.RUnlock()
+	return auditStd
+}
+
+func setAuditStd(a *auditLog) {
+	auditMu.Lock()
+	auditStd = a
+	auditMu.Unlock()
+}
@@ -1,12 +1,103 @@
 package glogger
 
 import (
+	"os"
+	"time"
+
+	"github.com/MSLibs/glogger/core/encoder"
+	"github.com/MSLibs/glogger/core/filter"
+	"github.com/MSLibs/glogger/core/redact"
+	"github.com/MSLibs/glogger/core/sink"
+
 	"go.uber.org/zap/zapcore"
 )
 
 type GLoggerConfig struct {
+	// OutputPath is a file path or a sink URL written alongside stdout.
+	// syslog:// and gelf:// URLs are written with the "syslog" and "gelf"
+	// encodings, other outputs with "kvpare".
 	OutputPath string
 	Level      zapcore.Level
+	// Service, Version and Environment identify the service on every
+	// entry, next to the detected hostname, PID and container ID.
+	Service     string
+	Version     string
+	Environment string
+	// Kubernetes configures the pod metadata fields, which are added when
+	// the downward-API environment variables or files are present.
+	Kubernetes KubernetesConfig
+	// Sinks are extra outputs written alongside stdout and OutputPath, each
+	// with its own level range and encoding.
+	Sinks []SinkConfig
+	// Async, when set, moves every write onto a background flusher.
+	Async *sink.AsyncConfig
+	// ReopenOnSignal reopens the file outputs whenever ReopenSignal (SIGHUP
+	// by default) arrives, for use with logrotate in create mode.
+	ReopenOnSignal bool
+	ReopenSignal   os.Signal
+	// Sampling and RateLimit thin out repetitive or bursty logging. While
+	// either is set, a summary of the suppressed entries is logged every
+	// SummaryInterval (one minute by default).
+	Sampling        *filter.SamplingConfig
+	RateLimit       *filter.RateLimitConfig
+	SummaryInterval time.Duration
+	// Redact rewrites personal data and secrets in every output before it is
+	// encoded. Sinks may override it with their own rules.
+	Redact *redact.Config
+	// Dedup collapses runs of identical consecutive entries into one line
+	// carrying a repeat count. Entries are held back for up to its Window.
+	Dedup *filter.DedupConfig
+	// Webhook forwards Error entries and above to an HTTP endpoint in JSON
+	// batches.
+	Webhook *sink.WebhookConfig
+	// Shipper sends entries in batches to a log store such as Loki or
+	// Elasticsearch. Loki streams are labelled service=Service when it sets
+	// no Labels.
+	Shipper *sink.ShipperConfig
+	// Audit, when set, opens a dedicated audit channel used by Audit.
+	Audit *AuditConfig
+	// Syslog configures the "syslog" encoding. Its SDKeys default to the
+	// context fields, which then go into an RFC 5424 SD-ELEMENT.
+	Syslog *encoder.SyslogConfig
+	// GELF configures the "gelf" encoding used for Graylog.
+	GELF *encoder.GELFConfig
+}
+
+// SinkConfig routes the entries whose level falls within [MinLevel, MaxLevel]
+// to Path. Levels are zap level names such as "debug" or "error".
+type SinkConfig struct {
+	// Path is a file path or a zap sink URL such as "stderr".
+	Path string
+	// MinLevel defaults to GLoggerConfig.Level when empty.
+	MinLevel string
+	// MaxLevel leaves the range unbounded when empty.
+	MaxLevel string
+	// Encoding is one of "kvpare" (default), "json", "console", "syslog" or
+	// "gelf". Paths with a syslog or gelf scheme default to the matching
+	// encoding.
+	Encoding string
+	// Chain, when set, links the entries of this sink into a tamper-evident
+	// hash chain. Only the "json" and "kvpare" encodings support it.
+	Chain *sink.ChainConfig
+	// Redact replaces GLoggerConfig.Redact for this sink.
+	Redact *redact.Config
 }
 
 var _ GLoggerConfig = GLoggerConfig{}
+
+// AuditConfig configures the audit channel. Audit entries are written
+// synchronously and are never sampled, rate limited, deduplicated or
+// filtered by level.
+type AuditConfig struct {
+	// Path defaults to ./logs/audit.log.
+	Path string
+	// Encoding is "json" by default, or "kvpare".
+	Encoding string
+	// SyncEvery entries are fsync'd together, 1 (every entry) by default. A
+	// partial batch is synced after SyncInterval, 100ms by default.
+	SyncEvery    int
+	SyncInterval time.Duration
+	// Chain, when set, links the audit entries into a tamper-evident hash
+	// chain.
+	Chain *sink.ChainConfig
+}
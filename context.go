@@ -9,8 +9,8 @@ import (
 func WithContext(ctx *context.Context) GLogger {
 	logger := zap.L()
 	return GLogger{
-		logger,
-		logger.Sugar(),
-		ctx,
+		log:     logger,
+		sugar:   logger.Sugar(),
+		context: ctx,
 	}
 }
@@ -0,0 +1,94 @@
+package encoder
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// GELFConfig configures the GELF encoder.
+type GELFConfig struct {
+	// Host defaults to os.Hostname.
+	Host string
+	// Fields are static additional fields added to every message. Their
+	// names are prefixed with an underscore like every other field.
+	Fields map[string]interface{}
+}
+
+// gelfInvalid matches the characters GELF does not allow in field names.
+var gelfInvalid = regexp.MustCompile(`[^\w.\-]`)
+
+// gelfEncoder renders entries as GELF 1.1 JSON documents. Entry fields
+// become additional fields: requestId is written as _requestId.
+type gelfEncoder struct {
+	*zapcore.MapObjectEncoder
+	host string
+}
+
+// NewGELFEncoder creates a GELF 1.1 encoder. Each document ends with a
+// newline, which the GELF sinks strip before framing.
+func NewGELFEncoder(cfg GELFConfig) zapcore.Encoder {
+	host := cfg.Host
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+	enc := &gelfEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), host: host}
+	for k, v := range cfg.Fields {
+		enc.Fields[k] = v
+	}
+	return enc
+}
+
+func (enc *gelfEncoder) Clone() zapcore.Encoder {
+	clone := &gelfEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), host: enc.host}
+	for k, v := range enc.Fields {
+		clone.Fields[k] = v
+	}
+	return clone
+}
+
+func (enc *gelfEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	m := zapcore.NewMapObjectEncoder()
+	for k, v := range enc.Fields {
+		m.Fields[k] = v
+	}
+	for i := range fields {
+		fields[i].AddTo(m)
+	}
+
+	doc := make(map[string]interface{}, len(m.Fields)+8)
+	for k, v := range m.Fields {
+		k = gelfInvalid.ReplaceAllString(k, "_")
+		if k == "id" {
+			// _id is reserved by GELF.
+			k = "id_"
+		}
+		doc["_"+k] = v
+	}
+	doc["version"] = "1.1"
+	doc["host"] = enc.host
+	doc["short_message"] = ent.Message
+	doc["timestamp"] = float64(ent.Time.UnixNano()/int64(1e6)) / 1e3
+	doc["level"] = Severity(ent.Level)
+	if ent.Stack != "" {
+		doc["full_message"] = ent.Message + "\n" + ent.Stack
+	}
+	if ent.LoggerName != "" {
+		doc["_logger"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		doc["_caller"] = ent.Caller.TrimmedPath()
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	buf := bufferPool.Get()
+	buf.Write(data)
+	buf.AppendByte('\n')
+	return buf, nil
+}
@@ -0,0 +1,229 @@
+package encoder
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// SyslogConfig configures the syslog encoder. Zero values fall back to
+// defaults.
+type SyslogConfig struct {
+	// Facility is a syslog facility name such as "user" (the default),
+	// "daemon" or "local0".
+	Facility string
+	// AppName defaults to the executable name.
+	AppName string
+	// Hostname defaults to os.Hostname.
+	Hostname string
+	// RFC3164 selects the legacy BSD format, which has no structured data.
+	RFC3164 bool
+	// SDID names the SD-ELEMENT carrying SDKeys, "glogger@32473" by default.
+	SDID string
+	// SDKeys lists the field keys lifted out of the message into the
+	// SD-ELEMENT.
+	SDKeys []string
+	// SDParams are static parameters added to every SD-ELEMENT.
+	SDParams map[string]string
+}
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// Severity maps a zap level to a syslog severity.
+func Severity(l zapcore.Level) int {
+	switch l {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel:
+		return 2
+	case zapcore.PanicLevel:
+		return 1
+	}
+	return 0
+}
+
+type syslogSettings struct {
+	facility int
+	appName  string
+	hostname string
+	pid      string
+	rfc3164  bool
+	sdID     string
+	sdKeys   map[string]bool
+	sdParams [][2]string
+}
+
+// syslogEncoder wraps another encoder, which renders the MSG part, with a
+// syslog header and structured data.
+type syslogEncoder struct {
+	zapcore.Encoder
+	*syslogSettings
+	sd [][2]string
+}
+
+// NewSyslogEncoder creates an encoder that frames the output of inner as a
+// syslog message.
+func NewSyslogEncoder(cfg SyslogConfig, inner zapcore.Encoder) (zapcore.Encoder, error) {
+	s := &syslogSettings{
+		facility: 1,
+		appName:  cfg.AppName,
+		hostname: cfg.Hostname,
+		pid:      strconv.Itoa(os.Getpid()),
+		rfc3164:  cfg.RFC3164,
+		sdID:     cfg.SDID,
+		sdKeys:   map[string]bool{},
+	}
+	if cfg.Facility != "" {
+		f, ok := facilities[strings.ToLower(cfg.Facility)]
+		if !ok {
+			return nil, fmt.Errorf("unknown syslog facility %q", cfg.Facility)
+		}
+		s.facility = f
+	}
+	if s.appName == "" {
+		s.appName = filepath.Base(os.Args[0])
+	}
+	if s.hostname == "" {
+		s.hostname, _ = os.Hostname()
+	}
+	if s.hostname == "" {
+		s.hostname = "-"
+	}
+	if s.sdID == "" {
+		s.sdID = "glogger@32473"
+	}
+	for _, k := range cfg.SDKeys {
+		s.sdKeys[k] = true
+	}
+	for k, v := range cfg.SDParams {
+		s.sdParams = append(s.sdParams, [2]string{k, v})
+	}
+	sort.Slice(s.sdParams, func(i, j int) bool { return s.sdParams[i][0] < s.sdParams[j][0] })
+	return &syslogEncoder{Encoder: inner, syslogSettings: s}, nil
+}
+
+// AddString lifts structured-data keys added through With out of the message.
+func (enc *syslogEncoder) AddString(key, val string) {
+	if enc.sdKeys[key] && !enc.rfc3164 {
+		enc.sd = append(enc.sd, [2]string{key, val})
+		return
+	}
+	enc.Encoder.AddString(key, val)
+}
+
+func (enc *syslogEncoder) AddInt64(key string, val int64) {
+	if enc.sdKeys[key] && !enc.rfc3164 {
+		enc.sd = append(enc.sd, [2]string{key, strconv.FormatInt(val, 10)})
+		return
+	}
+	enc.Encoder.AddInt64(key, val)
+}
+
+func (enc *syslogEncoder) Clone() zapcore.Encoder {
+	return &syslogEncoder{
+		Encoder:        enc.Encoder.Clone(),
+		syslogSettings: enc.syslogSettings,
+		sd:             append([][2]string(nil), enc.sd...),
+	}
+}
+
+func (enc *syslogEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	sd := enc.sd
+	rest := fields
+	if len(enc.sdKeys) > 0 && !enc.rfc3164 {
+		sd = append([][2]string(nil), enc.sd...)
+		rest = make([]zapcore.Field, 0, len(fields))
+		for _, f := range fields {
+			if !enc.sdKeys[f.Key] {
+				rest = append(rest, f)
+				continue
+			}
+			m := zapcore.NewMapObjectEncoder()
+			f.AddTo(m)
+			sd = append(sd, [2]string{f.Key, fmt.Sprint(m.Fields[f.Key])})
+		}
+	}
+	msg, err := enc.Encoder.EncodeEntry(ent, rest)
+	if err != nil {
+		return nil, err
+	}
+	defer msg.Free()
+
+	buf := bufferPool.Get()
+	buf.AppendByte('<')
+	buf.AppendInt(int64(enc.facility*8 + Severity(ent.Level)))
+	buf.AppendByte('>')
+	if enc.rfc3164 {
+		buf.AppendString(ent.Time.Format("Jan _2 15:04:05"))
+		buf.AppendByte(' ')
+		buf.AppendString(enc.hostname)
+		buf.AppendByte(' ')
+		buf.AppendString(enc.appName)
+		buf.AppendByte('[')
+		buf.AppendString(enc.pid)
+		buf.AppendString("]: ")
+	} else {
+		buf.AppendString("1 ")
+		buf.AppendString(ent.Time.Format("2006-01-02T15:04:05.000000Z07:00"))
+		buf.AppendByte(' ')
+		buf.AppendString(enc.hostname)
+		buf.AppendByte(' ')
+		buf.AppendString(enc.appName)
+		buf.AppendByte(' ')
+		buf.AppendString(enc.pid)
+		buf.AppendString(" - ")
+		enc.appendSD(buf, sd)
+		buf.AppendByte(' ')
+	}
+	buf.Write(bytes.TrimRight(msg.Bytes(), "\r\n"))
+	buf.AppendByte('\n')
+	return buf, nil
+}
+
+func (enc *syslogEncoder) appendSD(buf *buffer.Buffer, sd [][2]string) {
+	if len(sd) == 0 && len(enc.sdParams) == 0 {
+		buf.AppendByte('-')
+		return
+	}
+	buf.AppendByte('[')
+	buf.AppendString(enc.sdID)
+	for _, params := range [][][2]string{enc.sdParams, sd} {
+		for _, p := range params {
+			if p[1] == "" {
+				continue
+			}
+			buf.AppendByte(' ')
+			buf.AppendString(p[0])
+			buf.AppendString(`="`)
+			for i := 0; i < len(p[1]); i++ {
+				switch c := p[1][i]; c {
+				case '"', '\\', ']':
+					buf.AppendByte('\\')
+					buf.AppendByte(c)
+				default:
+					buf.AppendByte(c)
+				}
+			}
+			buf.AppendByte('"')
+		}
+	}
+	buf.AppendByte(']')
+}
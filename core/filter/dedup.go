@@ -0,0 +1,167 @@
+package filter
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DedupConfig collapses runs of identical consecutive entries.
+type DedupConfig struct {
+	// Window caps how long an entry is held back while its run is collapsed,
+	// ten seconds by default.
+	Window time.Duration
+}
+
+// NewDedup collapses a run of identical consecutive entries (same level,
+// message, caller, context and fields) into one line. The first entry of a
+// run is held back until a different entry arrives, its window expires or
+// Sync is called; it is then written once, with repeated set to the number
+// of entries in the run and first/last set to the times of the first and
+// last of them. An entry that was not repeated is written unchanged. DPanic,
+// Panic and Fatal entries are never held back. Only the latest entry is
+// remembered, so memory use is bounded.
+func NewDedup(core zapcore.Core, cfg DedupConfig) zapcore.Core {
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	return &dedup{Core: core, state: &dedupState{window: cfg.Window}}
+}
+
+type dedup struct {
+	zapcore.Core
+	state *dedupState
+	// context holds the fields added with With, which the wrapped core
+	// encodes out of sight.
+	context []zapcore.Field
+}
+
+type dedupState struct {
+	window  time.Duration
+	mu      sync.Mutex
+	pending *dedupRun
+	timer   *time.Timer
+}
+
+type dedupRun struct {
+	core        *dedup
+	ent         zapcore.Entry
+	fields      []zapcore.Field
+	first, last time.Time
+	repeated    int
+}
+
+func (d *dedup) With(fields []zapcore.Field) zapcore.Core {
+	context := make([]zapcore.Field, 0, len(d.context)+len(fields))
+	context = append(append(context, d.context...), fields...)
+	return &dedup{Core: d.Core.With(fields), state: d.state, context: context}
+}
+
+func (d *dedup) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if d.Enabled(ent.Level) {
+		return ce.AddCore(ent, d)
+	}
+	return ce
+}
+
+func (d *dedup) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	s := d.state
+	s.mu.Lock()
+	if p := s.pending; p != nil && p.matches(d, ent, fields) && ent.Time.Sub(p.first) < s.window {
+		p.repeated++
+		p.last = ent.Time
+		s.mu.Unlock()
+		return nil
+	}
+	done := s.take()
+	if ent.Level > zapcore.ErrorLevel {
+		// The process may not survive this entry: write it now.
+		s.mu.Unlock()
+		done.write()
+		return d.write(ent, fields)
+	}
+	s.pending = &dedupRun{
+		core:     d,
+		ent:      ent,
+		fields:   append([]zapcore.Field(nil), fields...),
+		first:    ent.Time,
+		last:     ent.Time,
+		repeated: 1,
+	}
+	s.timer = time.AfterFunc(s.window, s.flush)
+	s.mu.Unlock()
+
+	done.write()
+	return nil
+}
+
+func (d *dedup) Sync() error {
+	d.state.flush()
+	return d.Core.Sync()
+}
+
+// write passes the entry to the wrapped core, honouring its level checks.
+func (d *dedup) write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ce := d.Core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+// take detaches the pending run. The caller holds s.mu.
+func (s *dedupState) take() *dedupRun {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	p := s.pending
+	s.pending = nil
+	return p
+}
+
+func (s *dedupState) flush() {
+	s.mu.Lock()
+	p := s.take()
+	s.mu.Unlock()
+	p.write()
+}
+
+func (p *dedupRun) matches(d *dedup, ent zapcore.Entry, fields []zapcore.Field) bool {
+	if p.ent.Level != ent.Level || p.ent.Message != ent.Message || p.ent.LoggerName != ent.LoggerName ||
+		p.ent.Caller.Defined != ent.Caller.Defined || p.ent.Caller.File != ent.Caller.File ||
+		p.ent.Caller.Line != ent.Caller.Line {
+		return false
+	}
+	return equalFields(p.core.context, d.context) && equalFields(p.fields, fields)
+}
+
+func equalFields(a, b []zapcore.Field) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equals(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// write emits the line of a run. It is a no-op on a nil run.
+func (p *dedupRun) write() {
+	if p == nil {
+		return
+	}
+	if p.repeated == 1 {
+		p.core.write(p.ent, p.fields)
+		return
+	}
+	fields := append(p.fields,
+		zap.Int("repeated", p.repeated),
+		zap.Time("first", p.first),
+		zap.Time("last", p.last),
+	)
+	p.core.write(p.ent, fields)
+}
@@ -0,0 +1,80 @@
+package filter
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// RateLimitConfig is a token bucket refilled at Rate entries per second and
+// holding at most Burst tokens.
+type RateLimitConfig struct {
+	Rate float64
+	// Burst defaults to Rate rounded up.
+	Burst int
+}
+
+// NewRateLimiter drops entries once the logger exceeds the configured rate.
+// DPanic, Panic and Fatal entries are never limited. Cores derived through
+// With share the bucket.
+func NewRateLimiter(core zapcore.Core, cfg RateLimitConfig, stats *Stats) zapcore.Core {
+	burst := float64(cfg.Burst)
+	if burst <= 0 {
+		burst = cfg.Rate
+		if burst < 1 {
+			burst = 1
+		}
+	}
+	return &rateLimiter{
+		Core:   core,
+		bucket: &tokenBucket{rate: cfg.Rate, burst: burst, tokens: burst, last: time.Now()},
+		stats:  stats,
+	}
+}
+
+type rateLimiter struct {
+	zapcore.Core
+	bucket *tokenBucket
+	stats  *Stats
+}
+
+func (r *rateLimiter) With(fields []zapcore.Field) zapcore.Core {
+	return &rateLimiter{Core: r.Core.With(fields), bucket: r.bucket, stats: r.stats}
+}
+
+func (r *rateLimiter) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !r.Enabled(ent.Level) {
+		return ce
+	}
+	if ent.Level <= zapcore.ErrorLevel && !r.bucket.take(ent.Time) {
+		r.stats.addRateLimited(ent.Level)
+		return ce
+	}
+	return r.Core.Check(ent, ce)
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) take(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
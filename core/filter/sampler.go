@@ -0,0 +1,37 @@
+package filter
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingConfig keeps the first First entries with a given level and message
+// in each Interval, then every Thereafter-th one.
+type SamplingConfig struct {
+	// Interval defaults to one second.
+	Interval time.Duration
+	// First defaults to 100.
+	First int
+	// Thereafter defaults to 100.
+	Thereafter int
+}
+
+// NewSampler wraps core with zap's sampler and counts what it drops in stats.
+func NewSampler(core zapcore.Core, cfg SamplingConfig, stats *Stats) zapcore.Core {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.First <= 0 {
+		cfg.First = 100
+	}
+	if cfg.Thereafter <= 0 {
+		cfg.Thereafter = 100
+	}
+	return zapcore.NewSamplerWithOptions(core, cfg.Interval, cfg.First, cfg.Thereafter,
+		zapcore.SamplerHook(func(ent zapcore.Entry, dec zapcore.SamplingDecision) {
+			if dec&zapcore.LogDropped > 0 {
+				stats.addSampled(ent.Level)
+			}
+		}))
+}
@@ -0,0 +1,62 @@
+package filter
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const numLevels = int(zapcore.FatalLevel-zapcore.DebugLevel) + 1
+
+// Stats counts the entries suppressed by the filters of one logger, per
+// level.
+type Stats struct {
+	sampled     [numLevels]uint64
+	rateLimited [numLevels]uint64
+}
+
+// Sampled returns how many entries of the given level the sampler dropped.
+func (s *Stats) Sampled(level zapcore.Level) uint64 {
+	return load(&s.sampled, level)
+}
+
+// RateLimited returns how many entries of the given level the rate limiter
+// dropped.
+func (s *Stats) RateLimited(level zapcore.Level) uint64 {
+	return load(&s.rateLimited, level)
+}
+
+// Total returns every suppressed entry regardless of level or reason.
+func (s *Stats) Total() uint64 {
+	var n uint64
+	for l := zapcore.DebugLevel; l <= zapcore.FatalLevel; l++ {
+		n += s.Sampled(l) + s.RateLimited(l)
+	}
+	return n
+}
+
+func (s *Stats) addSampled(level zapcore.Level) {
+	add(&s.sampled, level)
+}
+
+func (s *Stats) addRateLimited(level zapcore.Level) {
+	add(&s.rateLimited, level)
+}
+
+func index(level zapcore.Level) (int, bool) {
+	i := int(level - zapcore.DebugLevel)
+	return i, i >= 0 && i < numLevels
+}
+
+func load(counts *[numLevels]uint64, level zapcore.Level) uint64 {
+	if i, ok := index(level); ok {
+		return atomic.LoadUint64(&counts[i])
+	}
+	return 0
+}
+
+func add(counts *[numLevels]uint64, level zapcore.Level) {
+	if i, ok := index(level); ok {
+		atomic.AddUint64(&counts[i], 1)
+	}
+}
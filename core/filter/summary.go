@@ -0,0 +1,47 @@
+package filter
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SummaryMessage is the message of the entries written by RunSummary.
+const SummaryMessage = "log entries suppressed"
+
+// RunSummary writes a Warn entry to core every interval in which stats
+// counted suppressed entries, and a final one once stop is closed. It blocks
+// until then.
+func RunSummary(core zapcore.Core, stats *Stats, interval time.Duration, stop <-chan struct{}) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var sampled, limited uint64
+	report := func() {
+		var s, l uint64
+		for lvl := zapcore.DebugLevel; lvl <= zapcore.FatalLevel; lvl++ {
+			s += stats.Sampled(lvl)
+			l += stats.RateLimited(lvl)
+		}
+		if s == sampled && l == limited {
+			return
+		}
+		ent := zapcore.Entry{Level: zapcore.WarnLevel, Time: time.Now(), Message: SummaryMessage}
+		if ce := core.Check(ent, nil); ce != nil {
+			ce.Write(zap.Uint64("sampled", s-sampled), zap.Uint64("ratelimited", l-limited))
+		}
+		sampled, limited = s, l
+	}
+	for {
+		select {
+		case <-ticker.C:
+			report()
+		case <-stop:
+			report()
+			return
+		}
+	}
+}
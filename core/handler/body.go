@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/MSLibs/glogger/core/redact"
+)
+
+// BodyCaptureConfig configures request and response body capture. Captured
+// bodies are logged on the access log as reqBody and respBody.
+type BodyCaptureConfig struct {
+	// MaxBytes bounds each captured body, 4KB by default. Longer bodies are
+	// cut and flagged with reqBodyTruncated or respBodyTruncated.
+	MaxBytes int
+	// ContentTypes lists the media types captured, JSON and form bodies by
+	// default. Types ending in +json are treated as JSON.
+	ContentTypes []string
+	// Redact is applied to the captured bodies, DefaultBodyRedact when nil.
+	// Key rules match JSON object keys and form parameter names.
+	Redact *redact.Config
+}
+
+// DefaultBodyRedact masks credentials by key and runs the default detectors.
+var DefaultBodyRedact = redact.Config{
+	Keys: []redact.KeyRule{
+		{Pattern: "*password*"},
+		{Pattern: "*passwd*"},
+		{Pattern: "*token*"},
+		{Pattern: "*secret*"},
+	},
+	Detectors: redact.DefaultDetectors,
+}
+
+type bodyCapture struct {
+	max      int
+	types    map[string]bool
+	redactor *redact.Redactor
+}
+
+func newBodyCapture(cfg BodyCaptureConfig) (*bodyCapture, error) {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 4 << 10
+	}
+	if cfg.ContentTypes == nil {
+		cfg.ContentTypes = []string{"application/json", "application/x-www-form-urlencoded"}
+	}
+	rc := DefaultBodyRedact
+	if cfg.Redact != nil {
+		rc = *cfg.Redact
+	}
+	redactor, err := redact.New(rc)
+	if err != nil {
+		return nil, err
+	}
+	bc := &bodyCapture{max: cfg.MaxBytes, types: map[string]bool{}, redactor: redactor}
+	for _, t := range cfg.ContentTypes {
+		bc.types[strings.ToLower(t)] = true
+	}
+	return bc, nil
+}
+
+// mediaType returns the media type of contentType if it is captured.
+func (bc *bodyCapture) mediaType(contentType string) (string, bool) {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	if bc.types[mt] {
+		return mt, true
+	}
+	if strings.HasSuffix(mt, "+json") && bc.types["application/json"] {
+		return "application/json", true
+	}
+	return "", false
+}
+
+// request reads up to max bytes of the body of r and puts them back in front
+// of the rest, so that the next handler sees the body unchanged.
+func (bc *bodyCapture) request(r *http.Request) (body []byte, truncated bool, ok bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, false, false
+	}
+	if _, ok := bc.mediaType(r.Header.Get("Content-Type")); !ok {
+		return nil, false, false
+	}
+	head, err := ioutil.ReadAll(io.LimitReader(r.Body, int64(bc.max)+1))
+	r.Body = readCloser{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+	if err != nil {
+		return nil, false, false
+	}
+	if len(head) > bc.max {
+		return head[:bc.max], true, true
+	}
+	return head, false, true
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// render redacts a captured body for logging.
+func (bc *bodyCapture) render(body []byte, contentType string, truncated bool) string {
+	mt, _ := bc.mediaType(contentType)
+	if mt == "application/json" && !truncated {
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err == nil {
+			if out, err := json.Marshal(bc.redactJSON(v)); err == nil {
+				return string(out)
+			}
+		}
+	}
+	if mt == "application/json" {
+		// Truncated or invalid JSON: match the members textually.
+		return bc.redactor.String(jsonMember.ReplaceAllStringFunc(string(body), bc.redactMember))
+	}
+	if !truncated {
+		switch mt {
+		case "application/x-www-form-urlencoded":
+			if form, err := url.ParseQuery(string(body)); err == nil {
+				return bc.redactForm(form)
+			}
+		}
+	}
+	return bc.redactor.String(string(body))
+}
+
+// jsonMember matches a "key": value pair, including a string value cut off
+// by truncation.
+var jsonMember = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+
+func (bc *bodyCapture) redactMember(m string) string {
+	sub := jsonMember.FindStringSubmatch(m)
+	s, ok := bc.redactor.Key(sub[1])
+	if !ok {
+		return m
+	}
+	if s == redact.Drop {
+		return `"` + sub[1] + `"` + sub[2] + `null`
+	}
+	return `"` + sub[1] + `"` + sub[2] + `"` + redact.Apply(s, strings.Trim(sub[3], `"`)) + `"`
+}
+
+func (bc *bodyCapture) redactJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if s, ok := bc.redactor.Key(k); ok {
+				if s == redact.Drop {
+					delete(v, k)
+					continue
+				}
+				raw, _ := json.Marshal(e)
+				if str, ok := e.(string); ok {
+					raw = []byte(str)
+				}
+				v[k] = redact.Apply(s, string(raw))
+				continue
+			}
+			v[k] = bc.redactJSON(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = bc.redactJSON(e)
+		}
+	case string:
+		return bc.redactor.String(v)
+	}
+	return v
+}
+
+// redactForm renders form like url.Values.Encode, but leaves the redacted
+// values unescaped for readability.
+func (bc *bodyCapture) redactForm(form url.Values) string {
+	keys := make([]string, 0, len(form))
+	for k := range form {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		s, ok := bc.redactor.Key(k)
+		if ok && s == redact.Drop {
+			continue
+		}
+		for _, v := range form[k] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(url.QueryEscape(k))
+			b.WriteByte('=')
+			if ok {
+				b.WriteString(redact.Apply(s, v))
+			} else if r := bc.redactor.String(v); r != v {
+				b.WriteString(r)
+			} else {
+				b.WriteString(url.QueryEscape(v))
+			}
+		}
+	}
+	return b.String()
+}
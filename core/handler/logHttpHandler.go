@@ -3,31 +3,148 @@ package handler
 import (
 	"context"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/MSLibs/glogger"
 	"github.com/MSLibs/glogger/utils"
+
+	"go.uber.org/zap"
 )
 
+// RequestLogConfig configures LogRequestHandlerWith.
+type RequestLogConfig struct {
+	// Sanitize masks secrets in the Url and Referer fields and in the
+	// captured headers before they enter the context.
+	Sanitize SanitizeConfig
+	// RequestHeaders lists the request headers, such as X-Platform, that are
+	// put into the context and so logged with every entry of the request.
+	// Headers denied by Sanitize are logged as ***.
+	RequestHeaders []string
+	// ResponseHeaders lists the response headers logged on the access log.
+	ResponseHeaders []string
+	// AccessLog logs one entry per request once the handler returns, with
+	// the status, the response size and the captured headers.
+	AccessLog bool
+	// TrustedProxies lists the CIDRs or addresses of the reverse proxies
+	// whose Forwarded, X-Forwarded-For and X-Real-Ip headers are believed.
+	// Without it, sourceip is the peer address of the connection.
+	TrustedProxies []string
+	// ServerIP supplies the serverip field, utils.DefaultServerIP when nil.
+	ServerIP *utils.ServerIPCache
+	// Body, when set, captures the request and response bodies onto the
+	// access log, which it turns on.
+	Body *BodyCaptureConfig
+}
+
+// AccessLogMessage is the message of access log entries.
+const AccessLogMessage = "request completed"
+
+// LogRequestHandler is LogRequestHandlerWith the default configuration.
 func LogRequestHandler(next http.Handler) http.Handler {
-	fn := func(w http.ResponseWriter, r *http.Request) {
-		ri := &glogger.LogPayload{
-			Method:    r.Method,
-			Url:       r.URL.String(),
-			Referer:   r.Header.Get("Referer"),
-			UserAgent: r.Header.Get("User-Agent"),
+	return LogRequestHandlerWith(RequestLogConfig{})(next)
+}
+
+// LogRequestHandlerWith returns a middleware that puts the request details
+// into the context used by the glogger With* functions. It panics if the
+// trusted proxies or the body redaction rules are invalid.
+func LogRequestHandlerWith(cfg RequestLogConfig) func(http.Handler) http.Handler {
+	rl := &requestLogger{cfg: cfg, sanitizer: NewSanitizer(cfg.Sanitize)}
+	ps, err := parseProxies(cfg.TrustedProxies)
+	if err != nil {
+		panic(err)
+	}
+	rl.proxies = ps
+	if rl.cfg.ServerIP == nil {
+		rl.cfg.ServerIP = utils.DefaultServerIP()
+	}
+	if cfg.Body != nil {
+		bc, err := newBodyCapture(*cfg.Body)
+		if err != nil {
+			panic(err)
 		}
-		ri.SourceIP = requestGetRemoteAddress(r)
-		// this runs handler h and captures information about
-		// HTTP request
-		// m := httpsnoop.CaptureMetrics()
-		ri.Size = r.ContentLength
-		ctx := initLogContext(r, ri)
-		// next
+		rl.body = bc
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rl.serve(next, w, r)
+		})
+	}
+}
+
+type requestLogger struct {
+	cfg       RequestLogConfig
+	sanitizer *Sanitizer
+	proxies   proxies
+	body      *bodyCapture
+}
+
+func (rl *requestLogger) serve(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	ri := &glogger.LogPayload{
+		Method:    r.Method,
+		Url:       rl.sanitizer.URL(r.URL.String()),
+		Referer:   rl.sanitizer.URL(r.Header.Get("Referer")),
+		UserAgent: r.Header.Get("User-Agent"),
+		Headers:   rl.headers(r.Header, rl.cfg.RequestHeaders),
+	}
+	ri.SourceIP = rl.proxies.clientIP(r)
+	ri.Size = r.ContentLength
+	ri.ServerIP = rl.cfg.ServerIP.Get()
+	ctx := initLogContext(r, ri)
+	if !rl.cfg.AccessLog && rl.body == nil {
 		next.ServeHTTP(w, r.WithContext(ctx))
+		return
+	}
+	var fields []zap.Field
+	if rl.body != nil {
+		if body, truncated, ok := rl.body.request(r); ok {
+			fields = append(fields, zap.String("reqBody", rl.body.render(body, r.Header.Get("Content-Type"), truncated)))
+			if truncated {
+				fields = append(fields, zap.Bool("reqBodyTruncated", true))
+			}
+		}
 	}
-	return http.HandlerFunc(fn)
+	rw := &responseWriter{ResponseWriter: w, status: http.StatusOK, capture: rl.body}
+	next.ServeHTTP(rw, r.WithContext(ctx))
+
+	fields = append(fields,
+		zap.Int("status", rw.status),
+		zap.Int64("bytes", rw.written),
+	)
+	if rw.capturing {
+		fields = append(fields, zap.String("respBody", rl.body.render(rw.body.Bytes(), rw.Header().Get("Content-Type"), rw.truncated)))
+		if rw.truncated {
+			fields = append(fields, zap.Bool("respBodyTruncated", true))
+		}
+	}
+	resp := rl.headers(rw.Header(), rl.cfg.ResponseHeaders)
+	names := make([]string, 0, len(resp))
+	for name := range resp {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fields = append(fields, zap.String("resp."+name, resp[name]))
+	}
+	glogger.WithContext(&ctx).Info(AccessLogMessage, fields...)
+}
+
+// headers picks the listed headers that are present, masking denied ones.
+func (rl *requestLogger) headers(h http.Header, names []string) map[string]string {
+	var picked map[string]string
+	for _, name := range names {
+		name = http.CanonicalHeaderKey(name)
+		values, ok := h[name]
+		if !ok {
+			continue
+		}
+		if picked == nil {
+			picked = make(map[string]string, len(names))
+		}
+		picked[name] = rl.sanitizer.Header(name, strings.Join(values, ", "))
+	}
+	return picked
 }
 
 func initLogContext(r *http.Request, info *glogger.LogPayload) context.Context {
@@ -40,37 +157,20 @@ func initLogContext(r *http.Request, info *glogger.LogPayload) context.Context {
 	ctx = context.WithValue(ctx, glogger.UserAgent, info.UserAgent)
 	ctx = context.WithValue(ctx, glogger.Size, info.Size)
 	ctx = context.WithValue(ctx, glogger.Duration, start)
-	ctx = context.WithValue(ctx, glogger.Url, r.URL.String())
-	ctx = context.WithValue(ctx, glogger.SourceIP, requestGetRemoteAddress(r))
-	if serverip, err := utils.ExternalIP(); err == nil {
-		ctx = context.WithValue(ctx, glogger.ServerIP, serverip)
-	}
-	return ctx
-}
-
-func requestGetRemoteAddress(r *http.Request) string {
-	hdr := r.Header
-	hdrRealIP := hdr.Get("X-Real-Ip")
-	hdrForwardedFor := hdr.Get("X-Forwarded-For")
-	if hdrRealIP == "" && hdrForwardedFor == "" {
-		return ipAddrFromRemoteAddr(r.RemoteAddr)
+	ctx = context.WithValue(ctx, glogger.Url, info.Url)
+	ctx = context.WithValue(ctx, glogger.SourceIP, info.SourceIP)
+	if info.Headers != nil {
+		ctx = context.WithValue(ctx, glogger.Headers, info.Headers)
 	}
-	if hdrForwardedFor != "" {
-		// X-Forwarded-For is potentially a list of addresses separated with ","
-		parts := strings.Split(hdrForwardedFor, ",")
-		for i, p := range parts {
-			parts[i] = strings.TrimSpace(p)
-		}
-		// TODO: should return first non-local address
-		return parts[0]
+	if info.ServerIP != "" {
+		ctx = context.WithValue(ctx, glogger.ServerIP, info.ServerIP)
 	}
-	return hdrRealIP
+	return ctx
 }
 
+// ipAddrFromRemoteAddr strips the port of a host:port pair such as
+// 192.0.2.1:8080 or [::1]:8080, keeping any IPv6 zone.
 func ipAddrFromRemoteAddr(s string) string {
-	idx := strings.LastIndex(s, ":")
-	if idx == -1 {
-		return s
-	}
-	return s[:idx]
+	host, _ := utils.SplitHostZone(s)
+	return host
 }
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/MSLibs/glogger/core/metrics"
+)
+
+// MetricsHandler serves the log volume counters of every logger created by
+// glogger.CreateLog in the Prometheus text exposition format.
+func MetricsHandler() http.Handler {
+	return RegistryHandler(metrics.Default)
+}
+
+// RegistryHandler serves the metrics of registry in the Prometheus text
+// exposition format.
+func RegistryHandler(registry *metrics.Registry) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		metrics.WriteText(w, registry.Gather())
+	}
+	return http.HandlerFunc(fn)
+}
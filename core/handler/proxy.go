@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/MSLibs/glogger/utils"
+)
+
+// proxies are the trusted reverse proxy networks.
+type proxies []*net.IPNet
+
+func parseProxies(list []string) (proxies, error) {
+	ps := make(proxies, 0, len(list))
+	for _, s := range list {
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", s)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			ps = append(ps, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %v", s, err)
+		}
+		ps = append(ps, n)
+	}
+	return ps, nil
+}
+
+func (ps proxies) trusted(addr string) bool {
+	_, ip := utils.SplitHostZone(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range ps {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client. The forwarding headers are
+// only consulted when the peer is a trusted proxy; their hops are then walked
+// from the nearest to the farthest, and the first one that is not a trusted
+// proxy is the client. A hop that is not an IP address, such as the
+// "unknown" or obfuscated identifiers of the Forwarded header, ends the walk
+// at the last address known.
+func (ps proxies) clientIP(r *http.Request) string {
+	peer := ipAddrFromRemoteAddr(r.RemoteAddr)
+	if !ps.trusted(peer) {
+		return peer
+	}
+	hops := forwardedFor(r.Header)
+	if len(hops) == 0 {
+		if real, ip := utils.SplitHostZone(strings.TrimSpace(r.Header.Get("X-Real-Ip"))); ip != nil {
+			return real
+		}
+		return peer
+	}
+	last := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		if _, ip := utils.SplitHostZone(hops[i]); ip == nil {
+			return last
+		}
+		if !ps.trusted(hops[i]) {
+			return hops[i]
+		}
+		last = hops[i]
+	}
+	// Every hop is a trusted proxy: the farthest one is the best guess.
+	return last
+}
+
+// forwardedFor lists the client hops of the request, farthest first, from
+// the RFC 7239 Forwarded header or else from X-Forwarded-For.
+func forwardedFor(h http.Header) []string {
+	var hops []string
+	if values := h.Values("Forwarded"); len(values) > 0 {
+		for _, v := range values {
+			for _, elem := range strings.Split(v, ",") {
+				for _, pair := range strings.Split(elem, ";") {
+					eq := strings.IndexByte(pair, '=')
+					if eq < 0 || !strings.EqualFold(strings.TrimSpace(pair[:eq]), "for") {
+						continue
+					}
+					hops = append(hops, forwardedNode(strings.TrimSpace(pair[eq+1:])))
+				}
+			}
+		}
+		return hops
+	}
+	for _, v := range h.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(v, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, forwardedNode(hop))
+			}
+		}
+	}
+	return hops
+}
+
+// forwardedNode strips the quotes and port of a Forwarded node such as
+// "[2001:db8::17]:4711" or 192.0.2.60.
+func forwardedNode(node string) string {
+	host, _ := utils.SplitHostZone(strings.Trim(node, `"`))
+	return host
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/MSLibs/glogger"
+
+	"go.uber.org/zap"
+)
+
+// PanicMessage is the message of the entries logged for recovered panics.
+const PanicMessage = "panic recovered"
+
+// RecoverConfig configures RecoverHandlerWith. Zero values fall back to
+// defaults.
+type RecoverConfig struct {
+	// Status, 500 by default, Body and ContentType make up the response sent
+	// in place of the one the handler did not finish. Body defaults to the
+	// status text.
+	Status      int
+	Body        string
+	ContentType string
+	// MaxSummaryBytes bounds the request summary logged with the panic, 1KB
+	// by default.
+	MaxSummaryBytes int
+	// Sanitize masks secrets in the URL of the summary.
+	Sanitize SanitizeConfig
+}
+
+// RecoverHandler is RecoverHandlerWith the default configuration.
+func RecoverHandler(next http.Handler) http.Handler {
+	return RecoverHandlerWith(RecoverConfig{})(next)
+}
+
+// RecoverHandlerWith returns a middleware that recovers panics in the next
+// handler, logs them at Error with the request's context fields and a
+// request summary, and answers with a 500. The stack trace is the one the
+// logger adds to every Error entry.
+// http.ErrAbortHandler is re-panicked so that net/http aborts the response
+// as intended. Handlers behind it can still hijack the connection.
+func RecoverHandlerWith(cfg RecoverConfig) func(http.Handler) http.Handler {
+	if cfg.Status == 0 {
+		cfg.Status = http.StatusInternalServerError
+	}
+	if cfg.Body == "" {
+		cfg.Body = http.StatusText(cfg.Status)
+	}
+	if cfg.ContentType == "" {
+		cfg.ContentType = "text/plain; charset=utf-8"
+	}
+	if cfg.MaxSummaryBytes <= 0 {
+		cfg.MaxSummaryBytes = 1 << 10
+	}
+	sanitizer := NewSanitizer(cfg.Sanitize)
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				ctx := r.Context()
+				glogger.WithContext(&ctx).Error(PanicMessage,
+					zap.String("panic", fmt.Sprint(p)),
+					zap.String("request", summary(r, sanitizer, cfg.MaxSummaryBytes)),
+				)
+				if rw.wroteHeader {
+					// Too late for a clean error response.
+					return
+				}
+				w.Header().Set("Content-Type", cfg.ContentType)
+				w.Header().Set("X-Content-Type-Options", "nosniff")
+				w.WriteHeader(cfg.Status)
+				fmt.Fprint(w, cfg.Body)
+			}()
+			next.ServeHTTP(rw, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// summary describes r on one line, cut to max bytes.
+func summary(r *http.Request, sanitizer *Sanitizer, max int) string {
+	s := fmt.Sprintf("%s %s %s host=%s remote=%s length=%d",
+		r.Method, sanitizer.URL(r.URL.RequestURI()), r.Proto, r.Host, r.RemoteAddr, r.ContentLength)
+	if ua := r.UserAgent(); ua != "" {
+		s += fmt.Sprintf(" ua=%q", ua)
+	}
+	if len(s) > max {
+		s = s[:max] + "..."
+	}
+	return s
+}
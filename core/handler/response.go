@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// responseWriter records the status and size of a response, and tees the
+// start of its body when capture is set.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	written     int64
+	wroteHeader bool
+
+	capture   *bodyCapture
+	checked   bool
+	capturing bool
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	if w.capture != nil {
+		w.tee(p)
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) tee(p []byte) {
+	if !w.checked {
+		w.checked = true
+		ct := w.Header().Get("Content-Type")
+		if ct == "" {
+			ct = http.DetectContentType(p)
+		}
+		_, w.capturing = w.capture.mediaType(ct)
+	}
+	if !w.capturing || w.truncated {
+		return
+	}
+	if room := w.capture.max - w.body.Len(); len(p) > room {
+		p = p[:room]
+		w.truncated = true
+	}
+	w.body.Write(p)
+}
+
+// Flush lets streaming handlers flush through the wrapper.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket and other upgrade handlers take over the connection
+// through the wrapper. A hijacked response is logged with status 101 unless
+// a status was written first.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("handler: the ResponseWriter does not support hijacking")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && !w.wroteHeader {
+		w.status = http.StatusSwitchingProtocols
+		w.wroteHeader = true
+	}
+	return conn, rw, err
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/MSLibs/glogger/core/redact"
+)
+
+// DefaultDenyParams are the query parameters masked when
+// SanitizeConfig.DenyParams is nil.
+var DefaultDenyParams = []string{
+	"access_token", "refresh_token", "id_token", "token", "sign", "signature",
+	"password", "passwd", "pwd", "secret", "client_secret", "api_key", "apikey",
+}
+
+// DefaultDenyHeaders are the headers masked when SanitizeConfig.DenyHeaders
+// is nil.
+var DefaultDenyHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key",
+}
+
+// SanitizeConfig says which query parameters and headers are masked before
+// they reach the log context. Names match case-insensitively.
+type SanitizeConfig struct {
+	// DenyParams lists the parameters whose values are rewritten to ***,
+	// DefaultDenyParams when nil.
+	DenyParams []string
+	// AllowParams, when set, masks every parameter it does not list instead.
+	AllowParams []string
+	// DenyHeaders lists the headers whose values are rewritten to ***,
+	// DefaultDenyHeaders when nil.
+	DenyHeaders []string
+}
+
+// Sanitizer applies a SanitizeConfig.
+type Sanitizer struct {
+	deny    map[string]bool
+	allow   map[string]bool
+	headers map[string]bool
+}
+
+// NewSanitizer compiles cfg.
+func NewSanitizer(cfg SanitizeConfig) *Sanitizer {
+	if cfg.DenyParams == nil {
+		cfg.DenyParams = DefaultDenyParams
+	}
+	if cfg.DenyHeaders == nil {
+		cfg.DenyHeaders = DefaultDenyHeaders
+	}
+	s := &Sanitizer{
+		deny:    lowerSet(cfg.DenyParams),
+		headers: map[string]bool{},
+	}
+	if cfg.AllowParams != nil {
+		s.allow = lowerSet(cfg.AllowParams)
+	}
+	for _, h := range cfg.DenyHeaders {
+		s.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	return s
+}
+
+func lowerSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[strings.ToLower(n)] = true
+	}
+	return set
+}
+
+// URL masks the sensitive query parameters of an absolute URL or request
+// URI, leaving the rest of it byte for byte as it was.
+func (s *Sanitizer) URL(raw string) string {
+	q := strings.IndexByte(raw, '?')
+	if q < 0 {
+		return raw
+	}
+	query, fragment := raw[q+1:], ""
+	if f := strings.IndexByte(query, '#'); f >= 0 {
+		query, fragment = query[:f], query[f:]
+	}
+	params := strings.Split(query, "&")
+	for i, p := range params {
+		name := p
+		if eq := strings.IndexByte(p, '='); eq >= 0 {
+			name = p[:eq]
+		}
+		if s.masked(name) {
+			params[i] = name + "=" + redact.Masked
+		}
+	}
+	return raw[:q+1] + strings.Join(params, "&") + fragment
+}
+
+func (s *Sanitizer) masked(param string) bool {
+	if param == "" {
+		return false
+	}
+	if unescaped, err := url.QueryUnescape(param); err == nil {
+		param = unescaped
+	}
+	name := strings.ToLower(param)
+	if s.allow != nil {
+		return !s.allow[name]
+	}
+	return s.deny[name]
+}
+
+// Header returns value, or *** if the header name is denied.
+func (s *Sanitizer) Header(name, value string) string {
+	if s.headers[http.CanonicalHeaderKey(name)] {
+		return redact.Masked
+	}
+	return value
+}
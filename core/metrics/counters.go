@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type entryKey struct {
+	level  zapcore.Level
+	logger string
+}
+
+// EntryCounter counts entries per level and logger name.
+type EntryCounter struct {
+	counts sync.Map // entryKey -> *uint64
+}
+
+func (c *EntryCounter) inc(level zapcore.Level, logger string) {
+	key := entryKey{level, logger}
+	v, ok := c.counts.Load(key)
+	if !ok {
+		v, _ = c.counts.LoadOrStore(key, new(uint64))
+	}
+	atomic.AddUint64(v.(*uint64), 1)
+}
+
+// Samples returns one sample per level and logger name seen so far.
+func (c *EntryCounter) Samples() []Sample {
+	var samples []Sample
+	c.counts.Range(func(k, v interface{}) bool {
+		key := k.(entryKey)
+		samples = append(samples, Sample{
+			Labels: []Label{{"level", key.level.String()}, {"logger", key.logger}},
+			Value:  float64(atomic.LoadUint64(v.(*uint64))),
+		})
+		return true
+	})
+	return samples
+}
+
+// NewCountingCore counts every entry that reaches core in c.
+func NewCountingCore(core zapcore.Core, c *EntryCounter) zapcore.Core {
+	return &countingCore{Core: core, counter: c}
+}
+
+type countingCore struct {
+	zapcore.Core
+	counter *EntryCounter
+}
+
+func (c *countingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &countingCore{Core: c.Core.With(fields), counter: c.counter}
+}
+
+func (c *countingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	ce = c.Core.Check(ent, ce)
+	if ce != nil {
+		ce = ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write only counts; Check has already added the wrapped core.
+func (c *countingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.counter.inc(ent.Level, ent.LoggerName)
+	return nil
+}
+
+// CountingWriter counts the bytes written through it.
+type CountingWriter struct {
+	zapcore.WriteSyncer
+	n uint64
+}
+
+func NewCountingWriter(ws zapcore.WriteSyncer) *CountingWriter {
+	return &CountingWriter{WriteSyncer: ws}
+}
+
+func (w *CountingWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteSyncer.Write(p)
+	atomic.AddUint64(&w.n, uint64(n))
+	return n, err
+}
+
+// Bytes returns the number of bytes written so far.
+func (w *CountingWriter) Bytes() uint64 {
+	return atomic.LoadUint64(&w.n)
+}
@@ -0,0 +1,169 @@
+// Package metrics keeps log volume counters and renders them in the
+// Prometheus text exposition format, without depending on the Prometheus
+// client.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Label is a metric label pair.
+type Label struct {
+	Name, Value string
+}
+
+// Sample is one value of a metric family.
+type Sample struct {
+	Labels []Label
+	Value  float64
+}
+
+// Family is a named metric with its samples. Type is a Prometheus metric
+// type such as "counter" or "gauge".
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Collector reports the current value of its metrics. Registries key
+// collectors by identity, so implementations should be pointer types.
+type Collector interface {
+	Collect() []Family
+}
+
+// Registry gathers metrics from a set of collectors.
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[Collector]struct{}
+}
+
+// Default is the registry every logger created by glogger.CreateLog reports
+// to.
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: map[Collector]struct{}{}}
+}
+
+func (r *Registry) Register(c Collector) {
+	r.mu.Lock()
+	r.collectors[c] = struct{}{}
+	r.mu.Unlock()
+}
+
+func (r *Registry) Unregister(c Collector) {
+	r.mu.Lock()
+	delete(r.collectors, c)
+	r.mu.Unlock()
+}
+
+// Gather collects every registered collector, merging families of the same
+// name and summing samples with identical labels, sorted by name.
+func (r *Registry) Gather() []Family {
+	r.mu.Lock()
+	collectors := make([]Collector, 0, len(r.collectors))
+	for c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mu.Unlock()
+
+	byName := map[string]*Family{}
+	index := map[string]map[string]int{}
+	for _, c := range collectors {
+		for _, f := range c.Collect() {
+			fam, ok := byName[f.Name]
+			if !ok {
+				fam = &Family{Name: f.Name, Help: f.Help, Type: f.Type}
+				byName[f.Name] = fam
+				index[f.Name] = map[string]int{}
+			}
+			for _, s := range f.Samples {
+				key := labelString(s.Labels)
+				if i, ok := index[f.Name][key]; ok {
+					fam.Samples[i].Value += s.Value
+					continue
+				}
+				index[f.Name][key] = len(fam.Samples)
+				fam.Samples = append(fam.Samples, s)
+			}
+		}
+	}
+	families := make([]Family, 0, len(byName))
+	for _, f := range byName {
+		sort.Slice(f.Samples, func(i, j int) bool {
+			return labelString(f.Samples[i].Labels) < labelString(f.Samples[j].Labels)
+		})
+		families = append(families, *f)
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	return families
+}
+
+// WriteText renders families in the Prometheus text exposition format.
+func WriteText(w io.Writer, families []Family) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		if f.Help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", f.Name, escapeHelp(f.Help))
+		}
+		if f.Type != "" {
+			fmt.Fprintf(bw, "# TYPE %s %s\n", f.Name, f.Type)
+		}
+		for _, s := range f.Samples {
+			bw.WriteString(f.Name)
+			bw.WriteString(labelString(s.Labels))
+			bw.WriteByte(' ')
+			bw.WriteString(formatValue(s.Value))
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+func labelString(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(l.Name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(l.Value))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
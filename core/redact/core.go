@@ -0,0 +1,171 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Fields returns fields with the key rules and detectors applied. The input
+// slice is left untouched.
+func (r *Redactor) Fields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		g, keep, changed := r.field(f)
+		if out == nil {
+			if !changed {
+				continue
+			}
+			out = make([]zapcore.Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		if keep {
+			out = append(out, g)
+		}
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// field returns the redacted f, whether to keep it and whether it changed.
+// Objects, arrays and reflected values are searched for matching keys and
+// strings too.
+func (r *Redactor) field(f zapcore.Field) (zapcore.Field, bool, bool) {
+	if s, ok := r.Key(f.Key); ok {
+		if s == Drop {
+			return f, false, true
+		}
+		return stringField(f.Key, Apply(s, value(f))), true, true
+	}
+	switch f.Type {
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.ReflectType:
+		if v, ok := r.nested(f); ok {
+			return zapcore.Field{Key: f.Key, Type: zapcore.ReflectType, Interface: v}, true, true
+		}
+		return f, true, false
+	}
+	if len(r.detectors) == 0 {
+		return f, true, false
+	}
+	switch f.Type {
+	case zapcore.StringType:
+		if v := r.String(f.String); v != f.String {
+			return stringField(f.Key, v), true, true
+		}
+	case zapcore.ByteStringType:
+		b := string(f.Interface.([]byte))
+		if v := r.String(b); v != b {
+			return stringField(f.Key, v), true, true
+		}
+	case zapcore.StringerType:
+		s := fmt.Sprint(f.Interface)
+		if v := r.String(s); v != s {
+			return stringField(f.Key, v), true, true
+		}
+	}
+	return f, true, false
+}
+
+// nested returns the redacted value of an object, array or reflected field,
+// if anything in it was redacted. The value is first normalized through
+// JSON, as the JSON encoder renders reflected values.
+func (r *Redactor) nested(f zapcore.Field) (interface{}, bool) {
+	m := zapcore.NewMapObjectEncoder()
+	f.AddTo(m)
+	raw, err := json.Marshal(m.Fields[f.Key])
+	if err != nil {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+	return r.walk(v)
+}
+
+// walk redacts the keys and strings of a decoded JSON value in place.
+func (r *Redactor) walk(v interface{}) (interface{}, bool) {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if s, ok := r.Key(k); ok {
+				changed = true
+				if s == Drop {
+					delete(v, k)
+					continue
+				}
+				if str, ok := e.(string); ok {
+					v[k] = Apply(s, str)
+				} else {
+					raw, _ := json.Marshal(e)
+					v[k] = Apply(s, string(raw))
+				}
+				continue
+			}
+			if e, ok := r.walk(e); ok {
+				v[k] = e
+				changed = true
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			if e, ok := r.walk(e); ok {
+				v[i] = e
+				changed = true
+			}
+		}
+	case string:
+		if s := r.String(v); s != v {
+			return s, true
+		}
+	}
+	return v, changed
+}
+
+func stringField(key, val string) zapcore.Field {
+	return zapcore.Field{Key: key, Type: zapcore.StringType, String: val}
+}
+
+// value renders the value of f the way an encoder would.
+func value(f zapcore.Field) string {
+	if f.Type == zapcore.StringType {
+		return f.String
+	}
+	m := zapcore.NewMapObjectEncoder()
+	f.AddTo(m)
+	return fmt.Sprint(m.Fields[f.Key])
+}
+
+// NewCore redacts the message and fields of every entry, including the
+// fields added through With, before core encodes them.
+func NewCore(core zapcore.Core, r *Redactor) zapcore.Core {
+	return &redactCore{Core: core, r: r}
+}
+
+type redactCore struct {
+	zapcore.Core
+	r *Redactor
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.r.Fields(fields)), r: c.r}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.r.String(ent.Message)
+	return c.Core.Write(ent, c.r.Fields(fields))
+}
@@ -0,0 +1,190 @@
+// Package redact removes personal data and secrets from log entries before
+// they are encoded.
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Strategy says how a sensitive value is rewritten.
+type Strategy string
+
+const (
+	// Mask replaces the value with "***". It is the default.
+	Mask Strategy = "mask"
+	// Hash replaces the value with a short SHA-256 digest, so that equal
+	// values can still be correlated.
+	Hash Strategy = "hash"
+	// Drop removes the field, or the matched text.
+	Drop Strategy = "drop"
+	// Partial keeps the first 3 and last 4 characters, as in 185****3365.
+	Partial Strategy = "partial"
+)
+
+// Masked is what Mask leaves of a value.
+const Masked = "***"
+
+// KeyRule redacts the fields whose key matches Pattern, either exactly or as
+// a path.Match glob such as "*password*". Keys nested in objects, maps and
+// structs logged with zap.Object or zap.Any are matched too.
+type KeyRule struct {
+	Pattern  string
+	Strategy Strategy
+}
+
+// Detector redacts the text matching a regular expression in string fields,
+// in the strings nested in object fields and in messages. Name selects a built-in pattern ("phone", "email",
+// "idcard", "creditcard" or "bearer") when Pattern is empty.
+type Detector struct {
+	Name     string
+	Pattern  string
+	Strategy Strategy
+}
+
+// Config lists the redaction rules.
+type Config struct {
+	Keys      []KeyRule
+	Detectors []Detector
+}
+
+var builtins = map[string]string{
+	"phone":      `\b1[3-9]\d{9}\b`,
+	"email":      `\b[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}\b`,
+	"idcard":     `\b\d{17}[\dXx]\b`,
+	"creditcard": `\b\d(?:[ \-]?\d){12,18}\b`,
+	"bearer":     `(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`,
+}
+
+// DefaultDetectors are the built-in detectors in the order they are applied.
+var DefaultDetectors = []Detector{
+	{Name: "bearer", Strategy: Mask},
+	{Name: "email", Strategy: Partial},
+	{Name: "idcard", Strategy: Partial},
+	{Name: "creditcard", Strategy: Partial},
+	{Name: "phone", Strategy: Partial},
+}
+
+// Redactor applies a compiled Config.
+type Redactor struct {
+	keys      []KeyRule
+	detectors []detector
+}
+
+type detector struct {
+	name     string
+	re       *regexp.Regexp
+	strategy Strategy
+}
+
+// New compiles cfg.
+func New(cfg Config) (*Redactor, error) {
+	r := &Redactor{}
+	for _, k := range cfg.Keys {
+		if _, err := path.Match(k.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("key pattern %q: %v", k.Pattern, err)
+		}
+		if err := checkStrategy(k.Strategy); err != nil {
+			return nil, err
+		}
+		r.keys = append(r.keys, k)
+	}
+	for _, d := range cfg.Detectors {
+		expr := d.Pattern
+		if expr == "" {
+			var ok bool
+			if expr, ok = builtins[d.Name]; !ok {
+				return nil, fmt.Errorf("unknown detector %q", d.Name)
+			}
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("detector %q: %v", d.Name, err)
+		}
+		if err := checkStrategy(d.Strategy); err != nil {
+			return nil, err
+		}
+		r.detectors = append(r.detectors, detector{name: d.Name, re: re, strategy: d.Strategy})
+	}
+	return r, nil
+}
+
+func checkStrategy(s Strategy) error {
+	switch s {
+	case "", Mask, Hash, Drop, Partial:
+		return nil
+	}
+	return fmt.Errorf("unknown redaction strategy %q", s)
+}
+
+// Key returns the strategy for the field key, if a key rule matches it.
+func (r *Redactor) Key(key string) (Strategy, bool) {
+	for _, k := range r.keys {
+		if k.Pattern == key {
+			return k.Strategy, true
+		}
+		if ok, _ := path.Match(k.Pattern, key); ok {
+			return k.Strategy, true
+		}
+	}
+	return "", false
+}
+
+// String rewrites the text matched by the detectors.
+func (r *Redactor) String(s string) string {
+	for _, d := range r.detectors {
+		s = d.re.ReplaceAllStringFunc(s, func(m string) string {
+			if d.name == "creditcard" && !luhn(m) {
+				return m
+			}
+			return Apply(d.strategy, m)
+		})
+	}
+	return s
+}
+
+// Apply rewrites v with strategy s. Drop yields "".
+func Apply(s Strategy, v string) string {
+	switch s {
+	case Drop:
+		return ""
+	case Hash:
+		sum := sha256.Sum256([]byte(v))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	case Partial:
+		n := utf8.RuneCountInString(v)
+		if n < 8 {
+			return Masked
+		}
+		runes := []rune(v)
+		return string(runes[:3]) + strings.Repeat("*", n-7) + string(runes[n-4:])
+	}
+	return Masked
+}
+
+// luhn reports whether the digits of s pass the Luhn check used by card
+// numbers.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,314 @@
+package sink
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// OverflowPolicy decides what an AsyncWriter does when its queue is full.
+type OverflowPolicy int
+
+const (
+	// Block makes the caller wait until the flusher frees a slot.
+	Block OverflowPolicy = iota
+	// DropNewest discards the entry being written.
+	DropNewest
+	// DropOldest evicts the oldest queued entry to make room.
+	DropOldest
+	// DropBelowLevel discards entries below AsyncConfig.DropLevel and blocks
+	// for the rest.
+	DropBelowLevel
+)
+
+// AsyncConfig configures an AsyncWriter. Zero values fall back to defaults.
+type AsyncConfig struct {
+	// QueueSize bounds the number of pending entries, 8192 by default.
+	QueueSize int
+	// FlushInterval is the longest an entry waits before being written,
+	// one second by default.
+	FlushInterval time.Duration
+	// FlushSize wakes the flusher early once this many entries are queued,
+	// 256 by default.
+	FlushSize int
+	Overflow  OverflowPolicy
+	DropLevel zapcore.Level
+}
+
+type asyncEntry struct {
+	level zapcore.Level
+	data  []byte
+}
+
+// AsyncWriter queues encoded entries in a bounded ring buffer and writes them
+// to the underlying WriteSyncer from a background goroutine.
+type AsyncWriter struct {
+	out zapcore.WriteSyncer
+	cfg AsyncConfig
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	ring     []asyncEntry
+	head     int
+	count    int
+	flushing bool
+	closed   bool
+	kick     chan struct{}
+	done     chan struct{}
+
+	written uint64
+	dropped [numLevels]uint64
+}
+
+const numLevels = int(zapcore.FatalLevel-zapcore.DebugLevel) + 1
+
+// NewAsyncWriter starts the flusher goroutine for out.
+func NewAsyncWriter(out zapcore.WriteSyncer, cfg AsyncConfig) *AsyncWriter {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 8192
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.FlushSize <= 0 {
+		cfg.FlushSize = 256
+	}
+	if cfg.FlushSize > cfg.QueueSize {
+		cfg.FlushSize = cfg.QueueSize
+	}
+	w := &AsyncWriter{
+		out:  out,
+		cfg:  cfg,
+		ring: make([]asyncEntry, cfg.QueueSize),
+		kick: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	w.cond = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+// Write queues p at InfoLevel.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	w.WriteLevel(zapcore.InfoLevel, p)
+	return len(p), nil
+}
+
+// WriteLevel queues a copy of p, applying the overflow policy when the queue
+// is full. It reports whether the entry was accepted.
+func (w *AsyncWriter) WriteLevel(level zapcore.Level, p []byte) bool {
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	w.mu.Lock()
+	for w.count == len(w.ring) && !w.closed {
+		policy := w.cfg.Overflow
+		if policy == DropBelowLevel {
+			if level < w.cfg.DropLevel {
+				policy = DropNewest
+			} else {
+				policy = Block
+			}
+		}
+		switch policy {
+		case DropNewest:
+			w.mu.Unlock()
+			w.drop(level)
+			return false
+		case DropOldest:
+			w.drop(w.ring[w.head].level)
+			w.ring[w.head] = asyncEntry{}
+			w.head = (w.head + 1) % len(w.ring)
+			w.count--
+		default:
+			w.signal()
+			w.cond.Wait()
+		}
+	}
+	if w.closed {
+		w.mu.Unlock()
+		// The flusher is gone; write through rather than lose the entry.
+		w.out.Write(data)
+		atomic.AddUint64(&w.written, 1)
+		return true
+	}
+	w.ring[(w.head+w.count)%len(w.ring)] = asyncEntry{level: level, data: data}
+	w.count++
+	full := w.count >= w.cfg.FlushSize
+	w.mu.Unlock()
+	if full {
+		w.signal()
+	}
+	return true
+}
+
+// Sync blocks until every queued entry is written, then syncs the
+// underlying WriteSyncer.
+func (w *AsyncWriter) Sync() error {
+	w.drain()
+	return w.out.Sync()
+}
+
+// Close drains the queue and stops the flusher. Entries written after Close
+// go straight to the underlying WriteSyncer.
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+	w.signal()
+	<-w.done
+	return w.out.Sync()
+}
+
+// Written returns the number of entries handed to the underlying writer.
+func (w *AsyncWriter) Written() uint64 {
+	return atomic.LoadUint64(&w.written)
+}
+
+// Dropped returns the number of entries discarded by the overflow policy.
+func (w *AsyncWriter) Dropped() uint64 {
+	var n uint64
+	for i := range w.dropped {
+		n += atomic.LoadUint64(&w.dropped[i])
+	}
+	return n
+}
+
+// DroppedAt returns the number of entries of the given level discarded by
+// the overflow policy.
+func (w *AsyncWriter) DroppedAt(level zapcore.Level) uint64 {
+	if i := int(level - zapcore.DebugLevel); i >= 0 && i < numLevels {
+		return atomic.LoadUint64(&w.dropped[i])
+	}
+	return 0
+}
+
+func (w *AsyncWriter) drop(level zapcore.Level) {
+	if i := int(level - zapcore.DebugLevel); i >= 0 && i < numLevels {
+		atomic.AddUint64(&w.dropped[i], 1)
+	}
+}
+
+func (w *AsyncWriter) drain() {
+	w.mu.Lock()
+	for (w.count > 0 || w.flushing) && !w.closed {
+		w.signal()
+		w.cond.Wait()
+	}
+	w.mu.Unlock()
+}
+
+func (w *AsyncWriter) signal() {
+	select {
+	case w.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.kick:
+		}
+		closed := w.flush()
+		if closed {
+			return
+		}
+	}
+}
+
+// flush writes out everything queued so far. Entries are written one per
+// Write, since network, syslog and GELF outputs send each Write as one
+// message.
+func (w *AsyncWriter) flush() bool {
+	w.mu.Lock()
+	closed := w.closed
+	if w.count == 0 {
+		w.mu.Unlock()
+		return closed
+	}
+	n := w.count
+	batch := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		idx := (w.head + i) % len(w.ring)
+		batch[i] = w.ring[idx].data
+		w.ring[idx] = asyncEntry{}
+	}
+	w.head = (w.head + n) % len(w.ring)
+	w.count = 0
+	w.flushing = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+
+	for _, data := range batch {
+		w.out.Write(data)
+	}
+	atomic.AddUint64(&w.written, uint64(n))
+
+	w.mu.Lock()
+	w.flushing = false
+	closed = w.closed
+	w.cond.Broadcast()
+	w.mu.Unlock()
+	if closed {
+		// Pick up anything queued while the last batch was being written.
+		return w.flush()
+	}
+	return false
+}
+
+// NewAsyncCore is like zapcore.NewCore but hands encoded entries, along with
+// their level, to an AsyncWriter.
+func NewAsyncCore(enc zapcore.Encoder, out *AsyncWriter, enab zapcore.LevelEnabler) zapcore.Core {
+	return &asyncCore{LevelEnabler: enab, enc: enc, out: out}
+}
+
+type asyncCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	out *AsyncWriter
+}
+
+func (c *asyncCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return &asyncCore{LevelEnabler: c.LevelEnabler, enc: enc, out: c.out}
+}
+
+func (c *asyncCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *asyncCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	c.out.WriteLevel(ent.Level, buf.Bytes())
+	buf.Free()
+	if ent.Level > zapcore.ErrorLevel {
+		// We may be about to crash, so don't leave the entry in the queue.
+		return c.Sync()
+	}
+	return nil
+}
+
+func (c *asyncCore) Sync() error {
+	return c.out.Sync()
+}
@@ -0,0 +1,56 @@
+package sink
+
+import (
+	"time"
+)
+
+// Backoff is an exponential retry schedule.
+type Backoff struct {
+	// Initial is the first delay, 500ms by default.
+	Initial time.Duration
+	// Max caps the delay, 30s by default.
+	Max time.Duration
+	// Retries is the number of retries after the first attempt, 5 by default.
+	Retries int
+}
+
+func (b Backoff) withDefaults() Backoff {
+	if b.Initial <= 0 {
+		b.Initial = 500 * time.Millisecond
+	}
+	if b.Max <= 0 {
+		b.Max = 30 * time.Second
+	}
+	if b.Retries <= 0 {
+		b.Retries = 5
+	}
+	return b
+}
+
+// Delay returns how long to wait before the given retry, counting from 0.
+func (b Backoff) Delay(retry int) time.Duration {
+	d := b.Initial
+	for i := 0; i < retry && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		d = b.Max
+	}
+	return d
+}
+
+// retry calls fn until it succeeds, reports a permanent failure, runs out of
+// retries or stop is closed.
+func (b Backoff) retry(stop <-chan struct{}, fn func() (retryable bool, err error)) error {
+	for i := 0; ; i++ {
+		retryable, err := fn()
+		if err == nil || !retryable || i >= b.Retries {
+			return err
+		}
+		select {
+		case <-time.After(b.Delay(i)):
+		case <-stop:
+			return err
+		}
+	}
+}
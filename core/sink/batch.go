@@ -0,0 +1,180 @@
+package sink
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// batchItem is one encoded entry waiting to be posted.
+type batchItem struct {
+	data   []byte
+	time   time.Time
+	labels map[string]string
+}
+
+// batcher collects items on a background goroutine and hands them to deliver in
+// batches, retrying with backoff and spooling batches that cannot be
+// delivered. It is shared by the webhook and HTTP shipping sinks.
+type batcher struct {
+	name     string
+	size     int
+	interval time.Duration
+	backoff  Backoff
+	spool    *Spool
+	errOut   zapcore.WriteSyncer
+	build    func([]batchItem) ([]byte, error)
+	deliver  func([]byte) (retryable bool, err error)
+
+	queue   chan batchItem
+	flushes chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+
+	dropped uint64
+}
+
+func (b *batcher) start(queueSize int) {
+	b.queue = make(chan batchItem, queueSize)
+	b.flushes = make(chan chan struct{})
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	go b.run()
+}
+
+func (b *batcher) add(item batchItem) {
+	select {
+	case b.queue <- item:
+	default:
+		atomic.AddUint64(&b.dropped, 1)
+	}
+}
+
+// Flush delivers or spools the current batch.
+func (b *batcher) Flush() {
+	ack := make(chan struct{})
+	select {
+	case b.flushes <- ack:
+		<-ack
+	case <-b.done:
+	}
+}
+
+// Close flushes the current batch, spooling it if the endpoint does not
+// accept it on the first attempt, and stops the delivery goroutine.
+func (b *batcher) Close() error {
+	b.once.Do(func() {
+		close(b.stop)
+		<-b.done
+	})
+	return nil
+}
+
+// Dropped returns the number of entries discarded because the queue was full.
+func (b *batcher) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+func (b *batcher) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	var batch []batchItem
+	for {
+		select {
+		case item := <-b.queue:
+			batch = append(batch, item)
+			if len(batch) >= b.size {
+				b.send(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				b.send(batch)
+				batch = nil
+			} else {
+				b.replay()
+			}
+		case ack := <-b.flushes:
+			b.send(b.drain(batch))
+			batch = nil
+			close(ack)
+		case <-b.stop:
+			b.send(b.drain(batch))
+			return
+		}
+	}
+}
+
+func (b *batcher) drain(batch []batchItem) []batchItem {
+	for {
+		select {
+		case item := <-b.queue:
+			batch = append(batch, item)
+		default:
+			return batch
+		}
+	}
+}
+
+func (b *batcher) send(batch []batchItem) {
+	if len(batch) == 0 {
+		return
+	}
+	body, err := b.build(batch)
+	if err != nil {
+		b.reportf("%s batch encoding failed, %d entries lost: %v", b.name, len(batch), err)
+		return
+	}
+	var retryable bool
+	err = b.backoff.retry(b.stop, func() (bool, error) {
+		var err error
+		retryable, err = b.deliver(body)
+		return retryable, err
+	})
+	if err == nil {
+		b.replay()
+		return
+	}
+	// A batch the endpoint rejected would be rejected again on replay and
+	// block the spool behind it, so only transient failures are spooled.
+	if b.spool != nil && retryable {
+		if serr := b.spool.Push(body); serr == nil {
+			return
+		}
+	}
+	b.reportf("%s delivery failed, %d entries lost: %v", b.name, len(batch), err)
+}
+
+// replay delivers spooled batches, oldest first, until one fails with a
+// retryable error. Batches the endpoint rejects are dropped and reported.
+func (b *batcher) replay() {
+	if b.spool == nil {
+		return
+	}
+	for {
+		name, body, ok := b.spool.Peek()
+		if !ok {
+			return
+		}
+		if retryable, err := b.deliver(body); err != nil {
+			if retryable {
+				return
+			}
+			b.reportf("%s rejected a spooled batch, dropping it: %v", b.name, err)
+		}
+		b.spool.Remove(name)
+	}
+}
+
+func (b *batcher) reportf(format string, args ...interface{}) {
+	if b.errOut == nil {
+		return
+	}
+	fmt.Fprintf(b.errOut, "%v "+format+"\n", append([]interface{}{time.Now()}, args...)...)
+	b.errOut.Sync()
+}
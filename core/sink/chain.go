@@ -0,0 +1,333 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ChainConfig configures a tamper-evident hash chain over a log file.
+type ChainConfig struct {
+	// Format is the encoding of the lines, "json" or "kvpare".
+	Format string
+	// Key, when set, turns every hash into an HMAC-SHA256, so that the chain
+	// cannot be recomputed without it.
+	Key []byte
+	// CheckpointEvery entries, a checkpoint line recording the number of
+	// entries chained so far is added, which verification checks against
+	// the entries it has seen. Zero disables checkpoints.
+	CheckpointEvery int
+}
+
+// CheckpointMessage is the message of checkpoint lines.
+const CheckpointMessage = "hash chain checkpoint"
+
+// genesis is the prev_hash of the first line of a chain.
+var genesis = strings.Repeat("0", sha256.Size*2)
+
+type chainFormat struct {
+	prev, hash func(h string) string
+	// checkpoint is the start of checkpoint lines, up to the entry count.
+	checkpoint     string
+	checkpointTime func(t time.Time) string
+}
+
+var chainFormats = map[string]chainFormat{
+	"json": {
+		prev:       func(h string) string { return `,"prev_hash":"` + h + `"}` },
+		hash:       func(h string) string { return `,"hash":"` + h + `"}` },
+		checkpoint: `{"msg":"` + CheckpointMessage + `","entries":`,
+		checkpointTime: func(t time.Time) string {
+			return `,"t":"` + t.UTC().Format(time.RFC3339Nano) + `"}`
+		},
+	},
+	"kvpare": {
+		prev:       func(h string) string { return "prev_hash?=" + h + "#" },
+		hash:       func(h string) string { return "hash?=" + h + "#" },
+		checkpoint: `#msg?="` + CheckpointMessage + `"#entries?=`,
+		checkpointTime: func(t time.Time) string {
+			return "#t?=" + t.UTC().Format(time.RFC3339Nano) + "#"
+		},
+	},
+}
+
+func (f chainFormat) formatCheckpoint(n int, t time.Time) string {
+	return f.checkpoint + strconv.Itoa(n) + f.checkpointTime(t)
+}
+
+// parseCheckpoint returns the entry count of a checkpoint line.
+func (f chainFormat) parseCheckpoint(line string) (int, bool) {
+	if !strings.HasPrefix(line, f.checkpoint) {
+		return 0, false
+	}
+	digits := line[len(f.checkpoint):]
+	end := 0
+	for end < len(digits) && digits[end] >= '0' && digits[end] <= '9' {
+		end++
+	}
+	n, err := strconv.Atoi(digits[:end])
+	return n, err == nil
+}
+
+// ChainPosition is a point in a chain: the hash of the last line and the
+// number of entries, checkpoints excluded, chained up to it. The zero value
+// is the start of a new chain.
+type ChainPosition struct {
+	Hash    string
+	Entries int
+}
+
+// ChainWriter adds prev_hash and hash fields to every line written through
+// it. The hash covers the line including its prev_hash, so removing,
+// reordering or editing a line breaks the chain from there on.
+type ChainWriter struct {
+	out    zapcore.WriteSyncer
+	cfg    ChainConfig
+	format chainFormat
+
+	mu      sync.Mutex
+	prev    string
+	entries int
+}
+
+// NewChainWriter continues the chain from pos, usually the ChainEnd of the
+// file out appends to. The zero ChainPosition starts a new chain.
+func NewChainWriter(out zapcore.WriteSyncer, cfg ChainConfig, pos ChainPosition) (*ChainWriter, error) {
+	format, ok := chainFormats[cfg.Format]
+	if !ok {
+		return nil, fmt.Errorf("hash chain does not support format %q", cfg.Format)
+	}
+	if pos.Hash == "" {
+		pos.Hash = genesis
+	}
+	return &ChainWriter{out: out, cfg: cfg, format: format, prev: pos.Hash, entries: pos.Entries}, nil
+}
+
+// ChainEnd returns the position at the end of the chain in path, or the
+// zero ChainPosition if the file is missing, empty or not chained, for
+// continuing the chain after a restart. The entry count is taken from the
+// last checkpoint; a file without checkpoints that continues a rotated file
+// only counts its own entries.
+func ChainEnd(path string, cfg ChainConfig) (ChainPosition, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return ChainPosition{}, nil
+	}
+	if err != nil {
+		return ChainPosition{}, err
+	}
+	defer f.Close()
+	return verify(f, cfg, ChainPosition{}, false)
+}
+
+func (w *ChainWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var buf bytes.Buffer
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte{'\n'}) {
+		w.appendLine(&buf, string(line))
+		w.entries++
+		if w.cfg.CheckpointEvery > 0 && w.entries%w.cfg.CheckpointEvery == 0 {
+			w.appendLine(&buf, w.format.formatCheckpoint(w.entries, time.Now()))
+		}
+	}
+	if _, err := w.out.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *ChainWriter) appendLine(buf *bytes.Buffer, line string) {
+	linked := w.link(line, w.format.prev, w.prev)
+	w.prev = sum(newChainHash(w.cfg.Key), linked)
+	buf.WriteString(w.link(linked, w.format.hash, w.prev))
+	buf.WriteByte('\n')
+}
+
+// link appends a field to line: JSON objects get it before the closing
+// brace, kv lines after the final separator.
+func (w *ChainWriter) link(line string, field func(string) string, h string) string {
+	if w.cfg.Format == "json" {
+		return strings.TrimSuffix(line, "}") + field(h)
+	}
+	return line + field(h)
+}
+
+func (w *ChainWriter) Sync() error {
+	return w.out.Sync()
+}
+
+func newChainHash(key []byte) hash.Hash {
+	if len(key) > 0 {
+		return hmac.New(sha256.New, key)
+	}
+	return sha256.New()
+}
+
+func sum(h hash.Hash, line string) string {
+	io.WriteString(h, line)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ChainError reports the first line of a file that breaks the hash chain.
+type ChainError struct {
+	// Path is set by VerifyChainFiles.
+	Path   string
+	Line   int
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("hash chain broken at %s:%d: %s", e.Path, e.Line, e.Reason)
+	}
+	return fmt.Sprintf("hash chain broken at line %d: %s", e.Line, e.Reason)
+}
+
+// VerifyChain reads a file written through a ChainWriter and returns a
+// *ChainError for the first line whose hash or prev_hash does not match, or
+// for the first checkpoint whose entry count differs from the entries seen.
+// The chain must start at the genesis hash, so lines removed from the head
+// of the file are detected too. Use VerifyChainFrom for rotated files.
+func VerifyChain(r io.Reader, cfg ChainConfig) error {
+	_, err := VerifyChainFrom(r, cfg, ChainPosition{})
+	return err
+}
+
+// VerifyChainFrom is VerifyChain for a file that continues the chain at
+// from, such as the position returned for the previous, rotated file. It
+// returns the position at the end of the file.
+func VerifyChainFrom(r io.Reader, cfg ChainConfig, from ChainPosition) (ChainPosition, error) {
+	return verify(r, cfg, from, true)
+}
+
+// VerifyChainFile is VerifyChain on the file at path.
+func VerifyChainFile(path string, cfg ChainConfig) error {
+	return VerifyChainFiles(cfg, path)
+}
+
+// VerifyChainFiles verifies a chain split over rotated files, given oldest
+// first. The first file must start the chain and every other one must
+// continue where the previous one ends.
+func VerifyChainFiles(cfg ChainConfig, paths ...string) error {
+	var pos ChainPosition
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		pos, err = VerifyChainFrom(f, cfg, pos)
+		f.Close()
+		if err != nil {
+			if ce, ok := err.(*ChainError); ok {
+				ce.Path = path
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// verify walks the chain from pos and returns the position at its end.
+// Unless strict, hashes are not checked and the entry count is taken from
+// the checkpoints.
+func verify(r io.Reader, cfg ChainConfig, pos ChainPosition, strict bool) (ChainPosition, error) {
+	format, ok := chainFormats[cfg.Format]
+	if !ok {
+		return pos, fmt.Errorf("hash chain does not support format %q", cfg.Format)
+	}
+	if pos.Hash == "" {
+		pos.Hash = genesis
+	}
+	hashLen := len(format.hash(genesis))
+	prevLen := len(format.prev(genesis))
+	closing := ""
+	if cfg.Format == "json" {
+		closing = "}"
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	last, n := "", 0
+	for scanner.Scan() {
+		n++
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		checkpoint, isCheckpoint := format.parseCheckpoint(line)
+		if !strict {
+			last = line
+			if isCheckpoint {
+				pos.Entries = checkpoint
+			} else {
+				pos.Entries++
+			}
+			continue
+		}
+		if len(line) < hashLen+prevLen {
+			return pos, &ChainError{Line: n, Reason: "missing hash"}
+		}
+		h := parseHash(line, format.hash)
+		prev := parseHash(line[:len(line)-hashLen]+closing, format.prev)
+		if h == "" || prev == "" {
+			return pos, &ChainError{Line: n, Reason: "missing hash"}
+		}
+		if prev != pos.Hash {
+			if last == "" {
+				return pos, &ChainError{Line: n, Reason: "prev_hash does not match the start of the chain"}
+			}
+			return pos, &ChainError{Line: n, Reason: "prev_hash does not match the previous line"}
+		}
+		if want := sum(newChainHash(cfg.Key), line[:len(line)-hashLen]+closing); h != want {
+			return pos, &ChainError{Line: n, Reason: "hash does not match the line"}
+		}
+		if !isCheckpoint {
+			pos.Entries++
+		} else if checkpoint != pos.Entries {
+			return pos, &ChainError{Line: n, Reason: fmt.Sprintf("checkpoint records %d entries, %d seen", checkpoint, pos.Entries)}
+		}
+		pos.Hash, last = h, line
+	}
+	if err := scanner.Err(); err != nil {
+		return pos, err
+	}
+	if !strict {
+		if h := parseHash(last, format.hash); h != "" {
+			return ChainPosition{Hash: h, Entries: pos.Entries}, nil
+		}
+		return ChainPosition{}, nil
+	}
+	return pos, nil
+}
+
+// parseHash extracts the hash of the field rendered by field at the end of
+// line, or returns "".
+func parseHash(line string, field func(string) string) string {
+	suffix := field(genesis)
+	if len(line) < len(suffix) {
+		return ""
+	}
+	tail := line[len(line)-len(suffix):]
+	start := strings.Index(suffix, genesis)
+	if tail[:start] != suffix[:start] || tail[start+len(genesis):] != suffix[start+len(genesis):] {
+		return ""
+	}
+	h := tail[start : start+len(genesis)]
+	if _, err := hex.DecodeString(h); err != nil {
+		return ""
+	}
+	return h
+}
@@ -0,0 +1,106 @@
+package sink
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DurableWriter syncs its output after every SyncEvery writes, and at the
+// latest SyncInterval after the first unsynced write, so that entries reach
+// the disk before the caller moves on. A sync that fails in the background
+// is returned by the next Write or Sync.
+type DurableWriter struct {
+	out      zapcore.WriteSyncer
+	every    int
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending int
+	timer   *time.Timer
+	closed  bool
+	err     error
+}
+
+// NewDurableWriter wraps out. An every of 1 or less syncs after each write;
+// interval defaults to 100ms and only matters when every is above 1.
+func NewDurableWriter(out zapcore.WriteSyncer, every int, interval time.Duration) *DurableWriter {
+	if every < 1 {
+		every = 1
+	}
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+	return &DurableWriter{out: out, every: every, interval: interval}
+}
+
+// Write writes p and, when the batch is full, syncs. A failed sync is
+// returned so that the logger reports it.
+func (w *DurableWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n, err := w.out.Write(p)
+	if err != nil {
+		return n, err
+	}
+	w.pending++
+	if w.pending >= w.every {
+		return n, w.syncErr()
+	}
+	if w.timer == nil && !w.closed {
+		w.timer = time.AfterFunc(w.interval, func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			w.timer = nil
+			if w.pending > 0 {
+				if err := w.sync(); err != nil && w.err == nil {
+					w.err = err
+				}
+			}
+		})
+	}
+	return n, w.takeErr()
+}
+
+func (w *DurableWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.syncErr()
+}
+
+// Close syncs what is left and stops the timer. It leaves out open.
+func (w *DurableWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return w.syncErr()
+}
+
+// syncErr syncs and returns the error of that sync or, failing that, of an
+// earlier background one. It is called with w.mu held.
+func (w *DurableWriter) syncErr() error {
+	err := w.sync()
+	if background := w.takeErr(); err == nil {
+		err = background
+	}
+	return err
+}
+
+// takeErr returns and clears the error of a failed background sync. It is
+// called with w.mu held.
+func (w *DurableWriter) takeErr() error {
+	err := w.err
+	w.err = nil
+	return err
+}
+
+// sync is called with w.mu held.
+func (w *DurableWriter) sync() error {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	w.pending = 0
+	return w.out.Sync()
+}
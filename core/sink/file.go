@@ -0,0 +1,77 @@
+package sink
+
+import (
+	"os"
+	"sync"
+)
+
+// ReopenableFile is an append-only file WriteSyncer that can swap its handle
+// for a freshly opened one, so that external tools such as logrotate can
+// move the file away underneath it.
+type ReopenableFile struct {
+	path string
+	mu   sync.RWMutex
+	f    *os.File
+}
+
+// OpenFile opens path for appending, creating it if needed.
+func OpenFile(path string) (*ReopenableFile, error) {
+	f, err := openAppend(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ReopenableFile{path: path, f: f}, nil
+}
+
+func openAppend(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+}
+
+func (f *ReopenableFile) Write(p []byte) (int, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.f == nil {
+		return 0, os.ErrClosed
+	}
+	return f.f.Write(p)
+}
+
+func (f *ReopenableFile) Sync() error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.f == nil {
+		return os.ErrClosed
+	}
+	return f.f.Sync()
+}
+
+// Reopen opens the path again and switches writes over to the new handle.
+// Writes in flight finish on the old handle before it is closed.
+func (f *ReopenableFile) Reopen() error {
+	nf, err := openAppend(f.path)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	old := f.f
+	if old == nil {
+		// Closed in the meantime; stay closed.
+		f.mu.Unlock()
+		return nf.Close()
+	}
+	f.f = nf
+	f.mu.Unlock()
+	old.Sync()
+	return old.Close()
+}
+
+func (f *ReopenableFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.f == nil {
+		return nil
+	}
+	err := f.f.Close()
+	f.f = nil
+	return err
+}
@@ -0,0 +1,184 @@
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const maxGELFChunks = 128
+
+// GELFConfig configures a GELFWriter.
+type GELFConfig struct {
+	Address string
+	// Compression is "gzip", "zlib" or "none" (the default).
+	Compression string
+	// ChunkSize caps the size of each datagram, 1420 bytes by default.
+	// Larger messages are split into GELF chunks.
+	ChunkSize    int
+	WriteTimeout time.Duration
+}
+
+// GELFWriter sends GELF documents over UDP, compressing and chunking them as
+// configured. Each line written is sent as a document of its own.
+type GELFWriter struct {
+	cfg GELFConfig
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func NewGELFWriter(cfg GELFConfig) (*GELFWriter, error) {
+	switch cfg.Compression {
+	case "", "none", "gzip", "zlib":
+	default:
+		return nil, fmt.Errorf("unknown GELF compression %q", cfg.Compression)
+	}
+	if cfg.ChunkSize <= 12 {
+		cfg.ChunkSize = 1420
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 5 * time.Second
+	}
+	return &GELFWriter{cfg: cfg}, nil
+}
+
+// NewGELFSink builds a GELF sink from a URL such as
+// gelf+udp://graylog:12201?compress=gzip&chunk=8154 or gelf+tcp://graylog:12201,
+// for use with zap.RegisterSink. TCP messages are null-byte terminated and
+// never compressed or chunked.
+func NewGELFSink(u *url.URL) (zap.Sink, error) {
+	q := u.Query()
+	switch u.Scheme {
+	case "gelf", "gelf+udp":
+		cfg := GELFConfig{Address: u.Host, Compression: q.Get("compress")}
+		if v := q.Get("chunk"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, err
+			}
+			cfg.ChunkSize = n
+		}
+		return NewGELFWriter(cfg)
+	case "gelf+tcp":
+		cfg := NetConfig{Network: "tcp", Address: u.Host, NullTerminated: true}
+		if err := parseNetQuery(&cfg, q); err != nil {
+			return nil, err
+		}
+		return NewNetWriter(cfg)
+	}
+	return nil, fmt.Errorf("unsupported GELF scheme %q", u.Scheme)
+}
+
+func (w *GELFWriter) Write(p []byte) (int, error) {
+	for _, doc := range bytes.Split(p, []byte{'\n'}) {
+		if len(doc) == 0 {
+			continue
+		}
+		if err := w.send(doc); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// send writes one document.
+func (w *GELFWriter) send(doc []byte) error {
+	msg, err := w.compress(doc)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		conn, err := net.DialTimeout("udp", w.cfg.Address, w.cfg.WriteTimeout)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+	}
+	for _, datagram := range w.chunk(msg) {
+		if datagram == nil {
+			return fmt.Errorf("GELF message of %d bytes needs more than %d chunks", len(msg), maxGELFChunks)
+		}
+		w.conn.SetWriteDeadline(time.Now().Add(w.cfg.WriteTimeout))
+		if _, err := w.conn.Write(datagram); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *GELFWriter) Sync() error {
+	return nil
+}
+
+func (w *GELFWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func (w *GELFWriter) compress(p []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	switch w.cfg.Compression {
+	case "gzip":
+		zw := gzip.NewWriter(&buf)
+		zw.Write(p)
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	case "zlib":
+		zw := zlib.NewWriter(&buf)
+		zw.Write(p)
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return append([]byte(nil), p...), nil
+	}
+	return buf.Bytes(), nil
+}
+
+// chunk splits msg into GELF chunks: magic bytes 0x1e 0x0f, an 8 byte
+// message id, the sequence number and the sequence count, then the payload.
+// It returns a single nil datagram if msg needs too many chunks.
+func (w *GELFWriter) chunk(msg []byte) [][]byte {
+	if len(msg) <= w.cfg.ChunkSize {
+		return [][]byte{msg}
+	}
+	size := w.cfg.ChunkSize - 12
+	count := (len(msg) + size - 1) / size
+	if count > maxGELFChunks {
+		return [][]byte{nil}
+	}
+	var id [8]byte
+	rand.Read(id[:])
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(msg) {
+			end = len(msg)
+		}
+		c := make([]byte, 0, 12+end-i*size)
+		c = append(c, 0x1e, 0x0f)
+		c = append(c, id[:]...)
+		c = append(c, byte(i), byte(count))
+		chunks = append(chunks, append(c, msg[i*size:end]...))
+	}
+	return chunks
+}
@@ -0,0 +1,349 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// NetConfig configures a NetWriter. Zero values fall back to defaults.
+type NetConfig struct {
+	// Network is "tcp", "udp", "unix" or "unixgram". For the unix networks
+	// Address is the socket path.
+	Network string
+	Address string
+	// WriteTimeout bounds each dial and send, 5s by default.
+	WriteTimeout time.Duration
+	// BufferSize bounds the bytes kept while the collector is unreachable,
+	// 1MB by default. The oldest messages are dropped first.
+	BufferSize int
+	// OctetCounting prefixes each TCP message with its length as described
+	// in RFC 6587 instead of relying on the trailing newline.
+	OctetCounting bool
+	// NullTerminated replaces the trailing newline of each TCP message with
+	// a null byte, as GELF over TCP expects.
+	NullTerminated bool
+	// Backoff spaces out reconnect attempts.
+	Backoff Backoff
+}
+
+// NetWriter ships each entry to a collector over TCP, UDP or a unix socket,
+// reconnecting with backoff and buffering while the connection is down.
+// Writes only queue the entry; a background goroutine dials and sends, so a
+// slow or unreachable collector never blocks the logging call.
+type NetWriter struct {
+	cfg NetConfig
+
+	mu       sync.Mutex
+	pending  [][]byte
+	buffered int
+	closed   bool
+
+	// Owned by the sending goroutine.
+	conn     net.Conn
+	failures int
+	retryAt  time.Time
+	closeErr error
+
+	kick  chan struct{}
+	syncs chan chan bool
+	stop  chan struct{}
+	done  chan struct{}
+
+	dropped uint64
+}
+
+// NewNetWriter starts the sending goroutine. The collector is dialed on the
+// first write.
+func NewNetWriter(cfg NetConfig) (*NetWriter, error) {
+	switch cfg.Network {
+	case "tcp", "udp", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("unsupported network %q", cfg.Network)
+	}
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("missing collector address")
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 5 * time.Second
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1 << 20
+	}
+	cfg.Backoff = cfg.Backoff.withDefaults()
+	w := &NetWriter{
+		cfg:   cfg,
+		kick:  make(chan struct{}, 1),
+		syncs: make(chan chan bool),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// NewNetSink builds a NetWriter from a URL such as
+// tcp://127.0.0.1:5170?timeout=2s&buffer=65536&framing=octet, for use with
+// zap.RegisterSink.
+func NewNetSink(u *url.URL) (zap.Sink, error) {
+	cfg := NetConfig{Network: u.Scheme, Address: u.Host}
+	if err := parseNetQuery(&cfg, u.Query()); err != nil {
+		return nil, err
+	}
+	return NewNetWriter(cfg)
+}
+
+// NewSyslogSink builds a NetWriter for a syslog daemon from a URL such as
+// syslog:///dev/log, syslog+udp://host:514 or syslog+tcp://host:601, for use
+// with zap.RegisterSink. The local socket is dialed as unixgram unless
+// ?network=unix is given; TCP uses octet-counting framing.
+func NewSyslogSink(u *url.URL) (zap.Sink, error) {
+	var cfg NetConfig
+	switch u.Scheme {
+	case "syslog":
+		cfg = NetConfig{Network: "unixgram", Address: u.Path}
+		if u.Path == "" {
+			cfg.Address = "/dev/log"
+		}
+		if n := u.Query().Get("network"); n != "" {
+			cfg.Network = n
+		}
+	case "syslog+udp":
+		cfg = NetConfig{Network: "udp", Address: u.Host}
+	case "syslog+tcp":
+		cfg = NetConfig{Network: "tcp", Address: u.Host, OctetCounting: true}
+	default:
+		return nil, fmt.Errorf("unsupported syslog scheme %q", u.Scheme)
+	}
+	if err := parseNetQuery(&cfg, u.Query()); err != nil {
+		return nil, err
+	}
+	return NewNetWriter(cfg)
+}
+
+func parseNetQuery(cfg *NetConfig, q url.Values) error {
+	if v := q.Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		cfg.WriteTimeout = d
+	}
+	if v := q.Get("buffer"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		cfg.BufferSize = n
+	}
+	switch q.Get("framing") {
+	case "":
+	case "lf":
+		cfg.OctetCounting = false
+	case "octet":
+		cfg.OctetCounting = true
+	default:
+		return fmt.Errorf("unknown framing %q", q.Get("framing"))
+	}
+	return nil
+}
+
+// Write queues p for the sending goroutine. It only fails once the writer
+// is closed.
+func (w *NetWriter) Write(p []byte) (int, error) {
+	msg := w.frame(p)
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return 0, fmt.Errorf("write to closed %s sink %s", w.cfg.Network, w.cfg.Address)
+	}
+	w.buffer(msg)
+	w.mu.Unlock()
+	select {
+	case w.kick <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Sync waits for the sending goroutine to try to send everything buffered.
+func (w *NetWriter) Sync() error {
+	reply := make(chan bool, 1)
+	select {
+	case w.syncs <- reply:
+		if <-reply {
+			return nil
+		}
+	case <-w.done:
+	}
+	w.mu.Lock()
+	n := len(w.pending)
+	w.mu.Unlock()
+	if n == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d messages still buffered for %s", n, w.cfg.Address)
+}
+
+// Close makes a last attempt to send what is buffered and stops the sending
+// goroutine.
+func (w *NetWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.stop)
+	<-w.done
+	return w.closeErr
+}
+
+// Dropped returns the number of messages evicted from a full buffer or
+// rejected as undeliverable, such as datagrams over the size limit.
+func (w *NetWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+func (w *NetWriter) frame(p []byte) []byte {
+	if w.cfg.Network != "tcp" || !(w.cfg.OctetCounting || w.cfg.NullTerminated) {
+		return append([]byte(nil), p...)
+	}
+	if n := len(p); n > 0 && p[n-1] == '\n' {
+		p = p[:n-1]
+	}
+	if w.cfg.NullTerminated {
+		return append(append([]byte(nil), p...), 0)
+	}
+	msg := strconv.AppendInt(nil, int64(len(p)), 10)
+	msg = append(msg, ' ')
+	return append(msg, p...)
+}
+
+// buffer queues msg. The caller holds w.mu.
+func (w *NetWriter) buffer(msg []byte) {
+	w.pending = append(w.pending, msg)
+	w.buffered += len(msg)
+	w.trim()
+}
+
+// requeue puts msg back in front of the queue after a failed send.
+func (w *NetWriter) requeue(msg []byte) {
+	w.mu.Lock()
+	w.pending = append([][]byte{msg}, w.pending...)
+	w.buffered += len(msg)
+	w.trim()
+	w.mu.Unlock()
+}
+
+// trim drops the oldest messages until the buffer fits. The caller holds
+// w.mu.
+func (w *NetWriter) trim() {
+	for w.buffered > w.cfg.BufferSize && len(w.pending) > 0 {
+		w.buffered -= len(w.pending[0])
+		w.pending[0] = nil
+		w.pending = w.pending[1:]
+		atomic.AddUint64(&w.dropped, 1)
+	}
+}
+
+func (w *NetWriter) run() {
+	defer close(w.done)
+	var retry <-chan time.Time
+	for {
+		var reply chan bool
+		select {
+		case <-w.kick:
+		case <-retry:
+		case reply = <-w.syncs:
+		case <-w.stop:
+			w.flush()
+			if w.conn != nil {
+				w.closeErr = w.conn.Close()
+			}
+			return
+		}
+		retry = nil
+		ok := w.flush()
+		if reply != nil {
+			reply <- ok
+		}
+		if !ok {
+			retry = time.After(time.Until(w.retryAt))
+		}
+	}
+}
+
+// flush sends the buffered messages in order and reports whether all of them
+// went out. It runs on the sending goroutine.
+func (w *NetWriter) flush() bool {
+	for {
+		w.mu.Lock()
+		if len(w.pending) == 0 {
+			w.pending = nil
+			w.mu.Unlock()
+			return true
+		}
+		msg := w.pending[0]
+		w.pending[0] = nil
+		w.pending = w.pending[1:]
+		w.buffered -= len(msg)
+		w.mu.Unlock()
+
+		if !w.connect() {
+			w.requeue(msg)
+			return false
+		}
+		w.conn.SetWriteDeadline(time.Now().Add(w.cfg.WriteTimeout))
+		_, err := w.conn.Write(msg)
+		if err != nil && !messageError(err) {
+			w.disconnect()
+			w.requeue(msg)
+			continue
+		}
+		if err != nil {
+			// Resending cannot succeed and would hold up the rest.
+			atomic.AddUint64(&w.dropped, 1)
+		}
+	}
+}
+
+// messageError reports whether err is caused by the message rather than the
+// connection, so that sending it again cannot succeed.
+func messageError(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE)
+}
+
+func (w *NetWriter) connect() bool {
+	if w.conn != nil {
+		return true
+	}
+	if time.Now().Before(w.retryAt) {
+		return false
+	}
+	conn, err := net.DialTimeout(w.cfg.Network, w.cfg.Address, w.cfg.WriteTimeout)
+	if err != nil {
+		w.retryAt = time.Now().Add(w.cfg.Backoff.Delay(w.failures))
+		w.failures++
+		return false
+	}
+	w.conn = conn
+	w.failures = 0
+	return true
+}
+
+func (w *NetWriter) disconnect() {
+	w.conn.Close()
+	w.conn = nil
+	w.retryAt = time.Now().Add(w.cfg.Backoff.Delay(w.failures))
+	w.failures++
+}
@@ -0,0 +1,326 @@
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ShipperConfig configures a Shipper. Zero values fall back to defaults.
+type ShipperConfig struct {
+	URL string
+	// Format is "ndjson" (the default), "bulk" for an Elasticsearch _bulk
+	// endpoint, which puts an index action before each entry, or "loki" for
+	// the Loki push API.
+	Format string
+	// Labels are static Loki stream labels, job="glogger" when empty since
+	// Loki rejects streams without labels. LabelKeys lists the fields, such
+	// as platformId, whose values become stream labels as well.
+	Labels    map[string]string
+	LabelKeys []string
+	// Gzip compresses request bodies.
+	Gzip bool
+	// Username and Password enable basic auth; BearerToken sets an
+	// Authorization: Bearer header instead.
+	Username    string
+	Password    string
+	BearerToken string
+	Headers     map[string]string
+	// MinLevel is the lowest level shipped, the logger level by default.
+	MinLevel string
+	// BatchSize entries, 500 by default, are posted together; a partial batch
+	// is posted after BatchInterval, 2s by default.
+	BatchSize     int
+	BatchInterval time.Duration
+	// Timeout bounds each request, 10s by default.
+	Timeout time.Duration
+	Backoff Backoff
+	// SpoolDir, when set, keeps batches that could not be delivered on disk,
+	// up to SpoolMaxBytes (64MB by default), until the endpoint recovers.
+	// Batches rejected with a non-retryable status are reported, not spooled.
+	SpoolDir      string
+	SpoolMaxBytes int64
+	// QueueSize bounds the entries waiting for a batch, 8192 by default.
+	QueueSize int
+	Client    *http.Client
+}
+
+// Shipper posts batches of entries to a log store such as Loki or
+// Elasticsearch.
+type Shipper struct {
+	*batcher
+	cfg         ShipperConfig
+	client      *http.Client
+	contentType string
+}
+
+// NewShipper starts the delivery goroutine. Delivery failures are reported
+// to errOut.
+func NewShipper(cfg ShipperConfig, errOut zapcore.WriteSyncer) (*Shipper, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("shipper URL is required")
+	}
+	s := &Shipper{cfg: cfg, contentType: "application/x-ndjson"}
+	build := s.ndjson
+	switch cfg.Format {
+	case "", "ndjson":
+	case "bulk":
+		build = s.bulk
+	case "loki":
+		build = s.loki
+		s.contentType = "application/json"
+		if len(cfg.Labels) == 0 {
+			cfg.Labels = map[string]string{"job": "glogger"}
+		}
+	default:
+		return nil, fmt.Errorf("unknown shipper format %q", cfg.Format)
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.BatchInterval <= 0 {
+		cfg.BatchInterval = 2 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 8192
+	}
+	s.cfg = cfg
+	s.client = cfg.Client
+	if s.client == nil {
+		s.client = &http.Client{Timeout: cfg.Timeout}
+	}
+	s.batcher = &batcher{
+		name:     "shipper",
+		size:     cfg.BatchSize,
+		interval: cfg.BatchInterval,
+		backoff:  cfg.Backoff.withDefaults(),
+		errOut:   errOut,
+		build:    s.compress(build),
+		deliver:  s.post,
+	}
+	if cfg.SpoolDir != "" {
+		spool, err := NewSpool(cfg.SpoolDir, cfg.SpoolMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		s.spool = spool
+	}
+	s.start(cfg.QueueSize)
+	return s, nil
+}
+
+func (s *Shipper) ndjson(batch []batchItem) ([]byte, error) {
+	var body bytes.Buffer
+	for _, item := range batch {
+		body.Write(item.data)
+		body.WriteByte('\n')
+	}
+	return body.Bytes(), nil
+}
+
+func (s *Shipper) bulk(batch []batchItem) ([]byte, error) {
+	var body bytes.Buffer
+	for _, item := range batch {
+		body.WriteString(`{"index":{}}` + "\n")
+		body.Write(item.data)
+		body.WriteByte('\n')
+	}
+	return body.Bytes(), nil
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// loki groups the batch into one stream per label set.
+func (s *Shipper) loki(batch []batchItem) ([]byte, error) {
+	var streams []*lokiStream
+	byKey := map[string]*lokiStream{}
+	for _, item := range batch {
+		labels := make(map[string]string, len(s.cfg.Labels)+len(item.labels))
+		for k, v := range s.cfg.Labels {
+			labels[k] = v
+		}
+		for k, v := range item.labels {
+			labels[k] = v
+		}
+		key := labelKey(labels)
+		st, ok := byKey[key]
+		if !ok {
+			st = &lokiStream{Stream: labels}
+			byKey[key] = st
+			streams = append(streams, st)
+		}
+		st.Values = append(st.Values, [2]string{
+			strconv.FormatInt(item.time.UnixNano(), 10),
+			string(item.data),
+		})
+	}
+	return json.Marshal(map[string]interface{}{"streams": streams})
+}
+
+func labelKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(labels[k])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// compress gzips the bodies built by build when the config asks for it.
+// Spooled batches are kept compressed.
+func (s *Shipper) compress(build func([]batchItem) ([]byte, error)) func([]batchItem) ([]byte, error) {
+	if !s.cfg.Gzip {
+		return build
+	}
+	return func(batch []batchItem) ([]byte, error) {
+		body, err := build(batch)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+func (s *Shipper) post(body []byte) (retryable bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", s.contentType)
+	if s.cfg.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if s.cfg.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.BearerToken)
+	} else if s.cfg.Username != "" {
+		req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("shipper endpoint responded %s", resp.Status)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// NewShipperCore ships the entries enabled by enab to s, encoded with enc.
+// The ndjson and bulk formats expect enc to produce JSON.
+func NewShipperCore(enc zapcore.Encoder, s *Shipper, enab zapcore.LevelEnabler) zapcore.Core {
+	keys := make(map[string]bool, len(s.cfg.LabelKeys))
+	for _, k := range s.cfg.LabelKeys {
+		keys[k] = true
+	}
+	return &shipperCore{LevelEnabler: enab, enc: enc, out: s, keys: keys}
+}
+
+type shipperCore struct {
+	zapcore.LevelEnabler
+	enc    zapcore.Encoder
+	out    *Shipper
+	keys   map[string]bool
+	labels map[string]string
+}
+
+func (c *shipperCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return &shipperCore{
+		LevelEnabler: c.LevelEnabler,
+		enc:          enc,
+		out:          c.out,
+		keys:         c.keys,
+		labels:       c.collect(c.labels, fields),
+	}
+}
+
+// collect returns labels extended with the non-empty values of the label
+// fields.
+func (c *shipperCore) collect(labels map[string]string, fields []zapcore.Field) map[string]string {
+	var m *zapcore.MapObjectEncoder
+	for i := range fields {
+		if !c.keys[fields[i].Key] {
+			continue
+		}
+		if m == nil {
+			m = zapcore.NewMapObjectEncoder()
+		}
+		fields[i].AddTo(m)
+	}
+	if m == nil {
+		return labels
+	}
+	out := make(map[string]string, len(labels)+len(m.Fields))
+	for k, v := range labels {
+		out[k] = v
+	}
+	for k, v := range m.Fields {
+		if v := fmt.Sprint(v); v != "" {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func (c *shipperCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *shipperCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	c.out.add(batchItem{
+		data:   append([]byte(nil), bytes.TrimSpace(buf.Bytes())...),
+		time:   ent.Time,
+		labels: c.collect(c.labels, fields),
+	})
+	buf.Free()
+	return nil
+}
+
+func (c *shipperCore) Sync() error {
+	c.out.Flush()
+	return nil
+}
@@ -0,0 +1,118 @@
+package sink
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Spool is a bounded on-disk FIFO of payloads that could not be delivered.
+// Each payload is one file; the oldest files are removed once the total size
+// exceeds the limit.
+type Spool struct {
+	dir string
+	max int64
+
+	mu  sync.Mutex
+	seq uint64
+}
+
+const spoolExt = ".spool"
+
+// NewSpool creates dir if needed. maxBytes defaults to 64MB.
+func NewSpool(dir string, maxBytes int64) (*Spool, error) {
+	if maxBytes <= 0 {
+		maxBytes = 64 << 20
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Spool{dir: dir, max: maxBytes}, nil
+}
+
+// Push stores payload, evicting the oldest payloads to stay within the limit.
+func (s *Spool) Push(payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), s.seq%1000000, spoolExt)
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := ioutil.WriteFile(tmp, payload, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		return err
+	}
+	return s.trim()
+}
+
+// Peek returns the oldest payload and its name, or ok=false when empty.
+func (s *Spool) Peek() (name string, payload []byte, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, _ := s.list()
+	for _, f := range files {
+		data, err := ioutil.ReadFile(filepath.Join(s.dir, f.Name()))
+		if err != nil {
+			continue
+		}
+		return f.Name(), data, true
+	}
+	return "", nil, false
+}
+
+// Remove deletes a payload returned by Peek once it has been delivered.
+func (s *Spool) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return os.Remove(filepath.Join(s.dir, name))
+}
+
+// Len returns the number of spooled payloads.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, _ := s.list()
+	return len(files)
+}
+
+func (s *Spool) list() ([]os.FileInfo, error) {
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	files := infos[:0]
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), spoolExt) {
+			files = append(files, info)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	return files, nil
+}
+
+func (s *Spool) trim() error {
+	files, err := s.list()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, f := range files {
+		total += f.Size()
+	}
+	for _, f := range files {
+		if total <= s.max {
+			break
+		}
+		if err := os.Remove(filepath.Join(s.dir, f.Name())); err != nil {
+			return err
+		}
+		total -= f.Size()
+	}
+	return nil
+}
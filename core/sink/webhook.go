@@ -0,0 +1,202 @@
+package sink
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// WebhookConfig configures a Webhook. Zero values fall back to defaults.
+type WebhookConfig struct {
+	URL     string
+	Headers map[string]string
+	// MinLevel is the lowest level forwarded, "error" by default.
+	MinLevel string
+	// BatchSize entries, 20 by default, are posted together; a partial batch
+	// is posted after BatchInterval, 5s by default.
+	BatchSize     int
+	BatchInterval time.Duration
+	// Timeout bounds each request, 10s by default.
+	Timeout time.Duration
+	Backoff Backoff
+	// SpoolDir, when set, keeps batches that could not be delivered on disk,
+	// up to SpoolMaxBytes (64MB by default), until the endpoint recovers.
+	// Batches rejected with a non-retryable status are reported, not spooled.
+	SpoolDir      string
+	SpoolMaxBytes int64
+	// AlertInterval forwards at most one entry per fingerprint (level,
+	// message and caller) per interval, one minute by default. A negative
+	// value disables the limit.
+	AlertInterval time.Duration
+	// QueueSize bounds the entries waiting for a batch, 1024 by default.
+	QueueSize int
+	Client    *http.Client
+}
+
+// Webhook posts entries to an HTTP endpoint as JSON arrays.
+type Webhook struct {
+	*batcher
+	cfg    WebhookConfig
+	client *http.Client
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewWebhook starts the delivery goroutine. Delivery failures are reported
+// to errOut.
+func NewWebhook(cfg WebhookConfig, errOut zapcore.WriteSyncer) (*Webhook, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 20
+	}
+	if cfg.BatchInterval <= 0 {
+		cfg.BatchInterval = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.AlertInterval == 0 {
+		cfg.AlertInterval = time.Minute
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1024
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+	w := &Webhook{
+		cfg:    cfg,
+		client: client,
+		seen:   map[string]time.Time{},
+	}
+	w.batcher = &batcher{
+		name:     "webhook",
+		size:     cfg.BatchSize,
+		interval: cfg.BatchInterval,
+		backoff:  cfg.Backoff.withDefaults(),
+		errOut:   errOut,
+		build:    jsonArray,
+		deliver:  w.post,
+	}
+	if cfg.SpoolDir != "" {
+		spool, err := NewSpool(cfg.SpoolDir, cfg.SpoolMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		w.spool = spool
+	}
+	w.start(cfg.QueueSize)
+	return w, nil
+}
+
+// allow applies the per-fingerprint alert limit.
+func (w *Webhook) allow(ent zapcore.Entry) bool {
+	if w.cfg.AlertInterval < 0 {
+		return true
+	}
+	key := ent.Level.String() + "|" + ent.Message + "|" + ent.Caller.File + ":" + strconv.Itoa(ent.Caller.Line)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if last, ok := w.seen[key]; ok && ent.Time.Sub(last) < w.cfg.AlertInterval {
+		return false
+	}
+	if len(w.seen) >= 4096 {
+		for k, t := range w.seen {
+			if ent.Time.Sub(t) >= w.cfg.AlertInterval {
+				delete(w.seen, k)
+			}
+		}
+		if len(w.seen) >= 4096 {
+			w.seen = map[string]time.Time{}
+		}
+	}
+	w.seen[key] = ent.Time
+	return true
+}
+
+func jsonArray(batch []batchItem) ([]byte, error) {
+	body := make([]byte, 0, 256*len(batch))
+	body = append(body, '[')
+	for i, item := range batch {
+		if i > 0 {
+			body = append(body, ',')
+		}
+		body = append(body, item.data...)
+	}
+	return append(body, ']'), nil
+}
+
+func (w *Webhook) post(body []byte) (retryable bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("webhook responded %s", resp.Status)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// NewWebhookCore forwards the entries enabled by enab to w, encoded with enc.
+// enc should produce JSON, such as zapcore.NewJSONEncoder.
+func NewWebhookCore(enc zapcore.Encoder, w *Webhook, enab zapcore.LevelEnabler) zapcore.Core {
+	return &webhookCore{LevelEnabler: enab, enc: enc, out: w}
+}
+
+type webhookCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	out *Webhook
+}
+
+func (c *webhookCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return &webhookCore{LevelEnabler: c.LevelEnabler, enc: enc, out: c.out}
+}
+
+func (c *webhookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *webhookCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.out.allow(ent) {
+		return nil
+	}
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	c.out.add(batchItem{data: append([]byte(nil), bytes.TrimSpace(buf.Bytes())...)})
+	buf.Free()
+	return nil
+}
+
+func (c *webhookCore) Sync() error {
+	c.out.Flush()
+	return nil
+}
@@ -3,10 +3,12 @@ package glogger
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"time"
 
 	"github.com/MSLibs/glogger/core/encoder"
+	"github.com/MSLibs/glogger/core/sink"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -29,12 +31,23 @@ const (
 	Url        string = "url"
 	ServerIP   string = "serverip"
 	SourceIP   string = "sourceip"
+	// Headers is the context key of the captured request headers, a
+	// map[string]string keyed by canonical header name. Each header is
+	// logged as a field named HeaderPrefix plus the header name.
+	Headers string = "headers"
 )
 
+// HeaderPrefix starts the field names of captured request headers.
+const HeaderPrefix = "req."
+
+// contextKeys are the keys of the fields GLogger fills in from the context.
+var contextKeys = []string{RequestID, UserFlag, PlatformID, Duration, Size, UserAgent, Referer, Method, Url, SourceIP, ServerIP}
+
 type GLogger struct {
 	log     *zap.Logger
 	sugar   *zap.SugaredLogger
 	context *context.Context
+	sinks   *sinkSet
 }
 
 type FormatTemplateWithor interface {
@@ -144,9 +157,39 @@ func defaultFields(ctx context.Context) []zap.Field {
 		zap.String(SourceIP, sourceip),
 		zap.String(ServerIP, serverip),
 	}
+	if h, ok := ctx.Value(Headers).(map[string]string); ok {
+		fileds = append(fileds, headerFields(h)...)
+	}
 	return fileds
 }
 
+// headerFields renders captured headers as fields, sorted by name.
+func headerFields(headers map[string]string) []zap.Field {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := make([]zap.Field, 0, len(names))
+	for _, name := range names {
+		fields = append(fields, zap.String(HeaderPrefix+name, headers[name]))
+	}
+	return fields
+}
+
+// Sync flushes every output, draining async queues first.
+func (log GLogger) Sync() error {
+	return log.log.Sync()
+}
+
+// Dropped returns how many entries the async overflow policy has discarded.
+func (log GLogger) Dropped() uint64 {
+	if log.sinks == nil {
+		return 0
+	}
+	return log.sinks.dropped()
+}
+
 func (log GLogger) With(fields ...zap.Field) GLogger {
 	log.log.With(fields...)
 	return log
@@ -296,10 +339,13 @@ func read(ctx context.Context) (payload LogPayload) {
 	if s, ok := ctx.Value(SourceIP).(string); ok {
 		payload.SourceIP = s
 	}
+	if h, ok := ctx.Value(Headers).(map[string]string); ok {
+		payload.Headers = h
+	}
 	return
 }
 func writeFields(payload LogPayload) []zap.Field {
-	return []zap.Field{
+	fields := []zap.Field{
 		zap.String(RequestID, payload.RequestID),
 		zap.String(UserFlag, payload.UserFlag),
 		zap.String(PlatformID, payload.PlatformID),
@@ -312,28 +358,53 @@ func writeFields(payload LogPayload) []zap.Field {
 		zap.String(SourceIP, payload.SourceIP),
 		zap.String(ServerIP, payload.ServerIP),
 	}
+	return append(fields, headerFields(payload.Headers)...)
 }
 
 var config zap.Config
 
 func CreateLog(gconfig GLoggerConfig) GLogger {
 	initDefaultConfig(gconfig)
-	logger, err := config.Build(zap.AddCallerSkip(1))
+	sinks := newSinkSet()
+	errSink, err := sinks.open(config.ErrorOutputPaths...)
+	if err != nil {
+		panic(err)
+	}
+	sinks.errOut = errSink
+	core, err := sinks.build(gconfig)
 	if err != nil {
-		logger.Error("logger construction falied")
 		panic(err)
 	}
+	metadata := metadataFields(gconfig)
+	if sinks.audit != nil {
+		sinks.audit.core = sinks.audit.core.With(metadata)
+	}
+	logger := zap.New(core,
+		zap.Fields(metadata...),
+		zap.ErrorOutput(errSink),
+		zap.AddCaller(),
+		zap.AddStacktrace(zapcore.ErrorLevel),
+		zap.AddCallerSkip(1),
+	)
 	zap.ReplaceGlobals(logger)
-	defer logger.Sync()
+	register(sinks)
+	if sinks.audit != nil {
+		setAuditStd(sinks.audit)
+	}
+	if gconfig.ReopenOnSignal {
+		sinks.reopenOn(gconfig.ReopenSignal)
+	}
 	logger.Info("logger construction succeeded")
 	return GLogger{
 		log:   logger,
 		sugar: logger.Sugar(),
+		sinks: sinks,
 	}
 }
 
 func initDefaultConfig(gconfig GLoggerConfig) {
 	registerEncoder()
+	registerSinks()
 	level := gconfig.Level
 	outputs := []string{"stdout"}
 	if gconfig.OutputPath == "" {
@@ -360,11 +431,6 @@ func initDefaultConfig(gconfig GLoggerConfig) {
 		},
 		OutputPaths:      outputs,
 		ErrorOutputPaths: []string{"stderr"},
-		// InitialFields: map[string]interface{}{
-		// 	"requestId":  "",
-		// 	"userflag":   "",
-		// 	"platformId": "",
-		// },
 	}
 }
 
@@ -378,6 +444,19 @@ func registerEncoder() {
 	})
 }
 
+// registerSinks makes the network, syslog and GELF sinks available to OutputPath and
+// SinkConfig.Path under their URL schemes.
+func registerSinks() {
+	zap.RegisterSink("tcp", sink.NewNetSink)
+	zap.RegisterSink("udp", sink.NewNetSink)
+	zap.RegisterSink("syslog", sink.NewSyslogSink)
+	zap.RegisterSink("syslog+udp", sink.NewSyslogSink)
+	zap.RegisterSink("syslog+tcp", sink.NewSyslogSink)
+	zap.RegisterSink("gelf", sink.NewGELFSink)
+	zap.RegisterSink("gelf+udp", sink.NewGELFSink)
+	zap.RegisterSink("gelf+tcp", sink.NewGELFSink)
+}
+
 type LogPayload struct {
 	RequestID  string
 	UserFlag   string
@@ -390,4 +469,5 @@ type LogPayload struct {
 	Url        string
 	ServerIP   string
 	SourceIP   string
+	Headers    map[string]string
 }
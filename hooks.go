@@ -0,0 +1,155 @@
+package glogger
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Hook inspects a written entry. The fields include the context fields
+// GLogger injects. A returned error or a panic is reported on the error
+// output and never reaches the logging call.
+type Hook func(zapcore.Entry, []zap.Field) error
+
+// AddHook runs hook synchronously, on the logging goroutine, for every entry
+// enabled by levels. A nil levels passes every entry; a plain level such as
+// zapcore.ErrorLevel passes that level and above.
+func (log GLogger) AddHook(hook Hook, levels zapcore.LevelEnabler) {
+	if log.sinks == nil {
+		return
+	}
+	log.sinks.hooks.add(&hookEntry{fn: hook, levels: levels})
+}
+
+// AddAsyncHook is like AddHook but hands entries to a background goroutine
+// through a queue of queueSize entries. Entries arriving while the queue is
+// full are skipped.
+func (log GLogger) AddAsyncHook(hook Hook, levels zapcore.LevelEnabler, queueSize int) {
+	if log.sinks == nil {
+		return
+	}
+	if queueSize <= 0 {
+		queueSize = 1024
+	}
+	h := &hookEntry{fn: hook, levels: levels, queue: make(chan hookCall, queueSize)}
+	s := log.sinks
+	s.goRun(func() {
+		for {
+			select {
+			case call := <-h.queue:
+				s.hooks.call(h, call)
+			case <-s.stop:
+				for {
+					select {
+					case call := <-h.queue:
+						s.hooks.call(h, call)
+					default:
+						return
+					}
+				}
+			}
+		}
+	})
+	s.hooks.add(h)
+}
+
+type hookCall struct {
+	ent    zapcore.Entry
+	fields []zap.Field
+}
+
+type hookEntry struct {
+	fn     Hook
+	levels zapcore.LevelEnabler
+	queue  chan hookCall
+}
+
+func (h *hookEntry) enabled(level zapcore.Level) bool {
+	return h.levels == nil || h.levels.Enabled(level)
+}
+
+type hookSet struct {
+	sinks *sinkSet
+	mu    sync.RWMutex
+	hooks []*hookEntry
+}
+
+func (hs *hookSet) add(h *hookEntry) {
+	hs.mu.Lock()
+	hs.hooks = append(hs.hooks, h)
+	hs.mu.Unlock()
+}
+
+func (hs *hookSet) enabled(level zapcore.Level) bool {
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+	for _, h := range hs.hooks {
+		if h.enabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (hs *hookSet) run(ent zapcore.Entry, fields []zap.Field) {
+	hs.mu.RLock()
+	hooks := hs.hooks
+	hs.mu.RUnlock()
+	for _, h := range hooks {
+		if !h.enabled(ent.Level) {
+			continue
+		}
+		if h.queue == nil {
+			hs.call(h, hookCall{ent, fields})
+			continue
+		}
+		select {
+		case h.queue <- hookCall{ent, append([]zap.Field(nil), fields...)}:
+		default:
+		}
+	}
+}
+
+// call runs one hook, turning errors and panics into error output.
+func (hs *hookSet) call(h *hookEntry, call hookCall) {
+	defer func() {
+		if r := recover(); r != nil {
+			hs.sinks.reportf("log hook panicked: %v", r)
+		}
+	}()
+	if err := h.fn(call.ent, call.fields); err != nil {
+		hs.sinks.reportf("log hook failed: %v", err)
+	}
+}
+
+// hookCore passes every entry that reaches the wrapped core to the hooks,
+// along with the fields added through With.
+type hookCore struct {
+	zapcore.Core
+	hooks   *hookSet
+	context []zap.Field
+}
+
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	context := make([]zap.Field, 0, len(c.context)+len(fields))
+	context = append(append(context, c.context...), fields...)
+	return &hookCore{Core: c.Core.With(fields), hooks: c.hooks, context: context}
+}
+
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	ce = c.Core.Check(ent, ce)
+	if c.hooks.enabled(ent.Level) {
+		ce = ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write only runs the hooks; Check has already added the wrapped core.
+func (c *hookCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if len(c.context) > 0 {
+		fields = append(append([]zap.Field(nil), c.context...), fields...)
+	}
+	c.hooks.run(ent, fields)
+	return nil
+}
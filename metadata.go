@@ -0,0 +1,87 @@
+package glogger
+
+import (
+	"os"
+
+	"github.com/MSLibs/glogger/utils"
+
+	"go.uber.org/zap"
+)
+
+// Keys of the static fields that identify the service instance.
+const (
+	Service     string = "service"
+	Version     string = "version"
+	Environment string = "env"
+	Hostname    string = "hostname"
+	PID         string = "pid"
+	ContainerID string = "containerId"
+)
+
+// metadataFields returns the fields attached once to every entry: the
+// configured service name, version and environment, and the detected
+// hostname, PID, container ID and pod metadata. Empty values are left out.
+func metadataFields(gconfig GLoggerConfig) []zap.Field {
+	var fields []zap.Field
+	add := func(key, val string) {
+		if val != "" {
+			fields = append(fields, zap.String(key, val))
+		}
+	}
+	add(Service, gconfig.Service)
+	add(Version, gconfig.Version)
+	add(Environment, gconfig.Environment)
+	host, _ := os.Hostname()
+	add(Hostname, host)
+	fields = append(fields, zap.Int(PID, os.Getpid()))
+	add(ContainerID, utils.ContainerID())
+	return append(fields, kubernetesFields(gconfig.Kubernetes)...)
+}
+
+// KubernetesConfig configures the pod metadata fields read from the
+// downward API. The zero value reads the standard sources.
+type KubernetesConfig struct {
+	// Disable skips the pod metadata.
+	Disable bool
+	// PodInfoDir is the downward-API volume, utils.DefaultPodInfoDir by
+	// default.
+	PodInfoDir string
+	// Labels lists the pod labels attached as fields.
+	Labels []string
+	// NamespaceKey, PodKey and NodeKey name the fields, "k8s.namespace",
+	// "k8s.pod" and "k8s.node" by default. LabelPrefix, "k8s.label." by
+	// default, is put in front of the label names.
+	NamespaceKey string
+	PodKey       string
+	NodeKey      string
+	LabelPrefix  string
+}
+
+func kubernetesFields(kc KubernetesConfig) []zap.Field {
+	if kc.Disable {
+		return nil
+	}
+	info := utils.ReadPodInfo(kc.PodInfoDir)
+	var fields []zap.Field
+	add := func(key, def, val string) {
+		if key == "" {
+			key = def
+		}
+		if val != "" {
+			fields = append(fields, zap.String(key, val))
+		}
+	}
+	add(kc.NamespaceKey, "k8s.namespace", info.Namespace)
+	add(kc.PodKey, "k8s.pod", info.Pod)
+	add(kc.NodeKey, "k8s.node", info.Node)
+	prefix := kc.LabelPrefix
+	if prefix == "" {
+		prefix = "k8s.label."
+	}
+	for _, label := range kc.Labels {
+		if val, ok := info.Labels[label]; ok {
+			fields = append(fields, zap.String(prefix+label, val))
+		}
+	}
+	return fields
+}
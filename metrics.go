@@ -0,0 +1,66 @@
+package glogger
+
+import (
+	"github.com/MSLibs/glogger/core/metrics"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type sinkBytes struct {
+	path string
+	w    *metrics.CountingWriter
+}
+
+// Collect reports the log volume of the logger to metrics.Default.
+func (s *sinkSet) Collect() []metrics.Family {
+	var suppressed []metrics.Sample
+	for l := zapcore.DebugLevel; l <= zapcore.FatalLevel; l++ {
+		var dropped uint64
+		for _, w := range s.async {
+			dropped += w.DroppedAt(l)
+		}
+		for _, c := range []struct {
+			reason string
+			n      uint64
+		}{
+			{"sampled", s.stats.Sampled(l)},
+			{"ratelimited", s.stats.RateLimited(l)},
+			{"dropped", dropped},
+		} {
+			if c.n == 0 {
+				continue
+			}
+			suppressed = append(suppressed, metrics.Sample{
+				Labels: []metrics.Label{{Name: "level", Value: l.String()}, {Name: "reason", Value: c.reason}},
+				Value:  float64(c.n),
+			})
+		}
+	}
+	written := make([]metrics.Sample, 0, len(s.written))
+	for _, b := range s.written {
+		written = append(written, metrics.Sample{
+			Labels: []metrics.Label{{Name: "sink", Value: b.path}},
+			Value:  float64(b.w.Bytes()),
+		})
+	}
+	return []metrics.Family{
+		{
+			Name:    "glogger_entries_total",
+			Help:    "Log entries written, by level and logger name.",
+			Type:    "counter",
+			Samples: s.entries.Samples(),
+		},
+		{
+			Name:    "glogger_entries_suppressed_total",
+			Help:    "Log entries sampled out, rate limited or dropped on overflow, by level.",
+			Type:    "counter",
+			Samples: suppressed,
+		},
+		{
+			Name:    "glogger_sink_bytes_total",
+			Help:    "Bytes written, by output path.",
+			Type:    "counter",
+			Samples: written,
+		},
+	}
+}
@@ -0,0 +1,39 @@
+package glogger
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Reopen reopens every file output of the logger on its original path. Call
+// it after an external tool has rotated the files.
+func (log GLogger) Reopen() error {
+	if log.sinks == nil {
+		return nil
+	}
+	return log.sinks.reopen()
+}
+
+// reopenOn reopens the file outputs each time sig arrives until the sink set
+// is closed.
+func (s *sinkSet) reopenOn(sig os.Signal) {
+	if sig == nil {
+		sig = syscall.SIGHUP
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig)
+	s.goRun(func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ch:
+				if err := s.reopen(); err != nil {
+					s.reportf("reopen log files: %v", err)
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package glogger
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/MSLibs/glogger/core/metrics"
+)
+
+// ShutdownTimeout bounds how long the signal handler waits for the outputs
+// to be flushed before letting the process exit.
+var ShutdownTimeout = 5 * time.Second
+
+var (
+	liveMu sync.Mutex
+	live   = map[*sinkSet]struct{}{}
+)
+
+func register(s *sinkSet) {
+	liveMu.Lock()
+	live[s] = struct{}{}
+	liveMu.Unlock()
+	metrics.Default.Register(s)
+}
+
+func unregister(s *sinkSet) {
+	liveMu.Lock()
+	delete(live, s)
+	liveMu.Unlock()
+	auditMu.Lock()
+	if s.audit != nil && auditStd == s.audit {
+		auditStd = nil
+	}
+	auditMu.Unlock()
+	metrics.Default.Unregister(s)
+}
+
+// Close flushes and closes every output opened by CreateLog for this logger,
+// giving up once ctx is done. The logger must not be used afterwards.
+func (log GLogger) Close(ctx context.Context) error {
+	if log.sinks == nil {
+		return log.Sync()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- log.sinks.close()
+	}()
+	select {
+	case err := <-done:
+		unregister(log.sinks)
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CloseAll closes every logger created by CreateLog that is still open.
+func CloseAll(ctx context.Context) error {
+	liveMu.Lock()
+	sets := make([]*sinkSet, 0, len(live))
+	for s := range live {
+		sets = append(sets, s)
+	}
+	liveMu.Unlock()
+
+	var first error
+	for _, s := range sets {
+		if err := (GLogger{sinks: s}).Close(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// SyncAll flushes every logger created by CreateLog that is still open,
+// leaving them usable, and gives up once ctx is done.
+func SyncAll(ctx context.Context) error {
+	liveMu.Lock()
+	sets := make([]*sinkSet, 0, len(live))
+	for s := range live {
+		sets = append(sets, s)
+	}
+	liveMu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		var first error
+		for _, s := range sets {
+			if s.top == nil {
+				continue
+			}
+			if err := s.top.Sync(); err != nil && first == nil {
+				first = err
+			}
+		}
+		done <- first
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SignalConfig configures InstallSignalHandlerWith.
+type SignalConfig struct {
+	// Signals defaults to SIGTERM and SIGINT.
+	Signals []os.Signal
+	// KeepRunning only flushes the open loggers when a signal arrives and
+	// leaves the process running, for applications that handle the signal
+	// themselves, such as to shut an HTTP server down gracefully. Without
+	// it, the loggers are closed and the signal is sent again with every
+	// handler for it removed, the application's own included.
+	KeepRunning bool
+}
+
+// InstallSignalHandler closes every open logger when the process receives one
+// of sigs (SIGTERM and SIGINT by default), then sends the signal again with
+// its handlers reset so that it terminates the process. Applications with
+// their own handler for these signals should use InstallSignalHandlerWith
+// and KeepRunning instead.
+func InstallSignalHandler(sigs ...os.Signal) {
+	InstallSignalHandlerWith(SignalConfig{Signals: sigs})
+}
+
+// InstallSignalHandlerWith is InstallSignalHandler configured by cfg.
+func InstallSignalHandlerWith(cfg SignalConfig) {
+	sigs := cfg.Signals
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGTERM, os.Interrupt}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	if cfg.KeepRunning {
+		go func() {
+			for range ch {
+				ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+				SyncAll(ctx)
+				cancel()
+			}
+		}()
+		return
+	}
+	go func() {
+		sig := <-ch
+		ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		CloseAll(ctx)
+		cancel()
+		signal.Stop(ch)
+		signal.Reset(sig)
+		if p, err := os.FindProcess(os.Getpid()); err == nil && p.Signal(sig) == nil {
+			return
+		}
+		os.Exit(1)
+	}()
+}
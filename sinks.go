@@ -0,0 +1,452 @@
+package glogger
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MSLibs/glogger/core/encoder"
+	"github.com/MSLibs/glogger/core/filter"
+	"github.com/MSLibs/glogger/core/metrics"
+	"github.com/MSLibs/glogger/core/redact"
+	"github.com/MSLibs/glogger/core/sink"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// sinkSet keeps track of the outputs opened by CreateLog and of the
+// goroutines serving them.
+type sinkSet struct {
+	core    zapcore.Core
+	top     zapcore.Core
+	async   []*sink.AsyncWriter
+	files   []*sink.ReopenableFile
+	closers []func()
+	errOut  zapcore.WriteSyncer
+	hooks   *hookSet
+	audit   *auditLog
+	stats   filter.Stats
+	entries metrics.EntryCounter
+	written []sinkBytes
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+	err     error
+}
+
+func newSinkSet() *sinkSet {
+	s := &sinkSet{stop: make(chan struct{})}
+	s.hooks = &hookSet{sinks: s}
+	return s
+}
+
+// goRun runs fn in a goroutine that close waits for.
+func (s *sinkSet) goRun(fn func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		fn()
+	}()
+}
+
+// build tees the default stdout/OutputPath core with one core per configured
+// sink, then applies the metrics, the hooks and the sampling, rate limiting
+// and dedup filters.
+func (s *sinkSet) build(gconfig GLoggerConfig) (zapcore.Core, error) {
+	cores, err := s.buildMainCores(gconfig)
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	for _, sc := range gconfig.Sinks {
+		core, err := s.buildSinkCore(gconfig, sc)
+		if err != nil {
+			s.close()
+			return nil, fmt.Errorf("sink %q: %v", sc.Path, err)
+		}
+		cores = append(cores, core)
+	}
+	if gconfig.Webhook != nil {
+		core, err := s.buildWebhookCore(gconfig, *gconfig.Webhook)
+		if err != nil {
+			s.close()
+			return nil, fmt.Errorf("webhook %q: %v", gconfig.Webhook.URL, err)
+		}
+		cores = append(cores, core)
+	}
+	if gconfig.Shipper != nil {
+		core, err := s.buildShipperCore(gconfig, *gconfig.Shipper)
+		if err != nil {
+			s.close()
+			return nil, fmt.Errorf("shipper %q: %v", gconfig.Shipper.URL, err)
+		}
+		cores = append(cores, core)
+	}
+	if gconfig.Audit != nil {
+		if err := s.buildAudit(gconfig, *gconfig.Audit); err != nil {
+			s.close()
+			return nil, fmt.Errorf("audit %q: %v", gconfig.Audit.Path, err)
+		}
+	}
+	s.core = zapcore.NewTee(cores...)
+	counted := metrics.NewCountingCore(s.core, &s.entries)
+	s.top = s.filter(gconfig, &hookCore{Core: counted, hooks: s.hooks})
+	return s.top, nil
+}
+
+// buildMainCores builds the cores of stdout and OutputPath. Outputs whose
+// scheme requires its own encoding, such as a syslog:// OutputPath, get a
+// core of their own; the others share config.Encoding.
+func (s *sinkSet) buildMainCores(gconfig GLoggerConfig) ([]zapcore.Core, error) {
+	byEncoding := map[string][]string{}
+	encodings := []string{}
+	for _, p := range config.OutputPaths {
+		encoding := pathEncoding(p, config.Encoding)
+		if byEncoding[encoding] == nil {
+			encodings = append(encodings, encoding)
+		}
+		byEncoding[encoding] = append(byEncoding[encoding], p)
+	}
+	cores := make([]zapcore.Core, 0, len(encodings))
+	for _, encoding := range encodings {
+		enc, err := newEncoder(gconfig, encoding)
+		if err != nil {
+			return nil, err
+		}
+		out, err := s.open(byEncoding[encoding]...)
+		if err != nil {
+			return nil, err
+		}
+		core, err := redactCore(s.newCore(gconfig, enc, out, config.Level), gconfig.Redact)
+		if err != nil {
+			return nil, err
+		}
+		cores = append(cores, core)
+	}
+	return cores, nil
+}
+
+func (s *sinkSet) filter(gconfig GLoggerConfig, core zapcore.Core) zapcore.Core {
+	filtered := false
+	if gconfig.Sampling != nil {
+		core = filter.NewSampler(core, *gconfig.Sampling, &s.stats)
+		filtered = true
+	}
+	if gconfig.RateLimit != nil && gconfig.RateLimit.Rate > 0 {
+		core = filter.NewRateLimiter(core, *gconfig.RateLimit, &s.stats)
+		filtered = true
+	}
+	if filtered {
+		out := s.core
+		s.goRun(func() {
+			filter.RunSummary(out, &s.stats, gconfig.SummaryInterval, s.stop)
+		})
+	}
+	if gconfig.Dedup != nil {
+		core = filter.NewDedup(core, *gconfig.Dedup)
+	}
+	return core
+}
+
+func (s *sinkSet) buildSinkCore(gconfig GLoggerConfig, sc SinkConfig) (zapcore.Core, error) {
+	enabler, err := levelRange(sc.MinLevel, sc.MaxLevel, gconfig.Level)
+	if err != nil {
+		return nil, err
+	}
+	encoding := sc.Encoding
+	if encoding == "" {
+		encoding = pathEncoding(sc.Path, config.Encoding)
+	}
+	enc, err := newEncoder(gconfig, encoding)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.open(sc.Path)
+	if err != nil {
+		return nil, err
+	}
+	if sc.Chain != nil {
+		if out, err = chain(sc.Path, encoding, *sc.Chain, out); err != nil {
+			return nil, err
+		}
+	}
+	rc := gconfig.Redact
+	if sc.Redact != nil {
+		rc = sc.Redact
+	}
+	return redactCore(s.newCore(gconfig, enc, out, enabler), rc)
+}
+
+// pathEncoding returns the encoding the scheme of path requires, "syslog"
+// for syslog:// and "gelf" for gelf:// URLs, or fallback for other paths.
+func pathEncoding(path, fallback string) string {
+	u, err := url.Parse(path)
+	if err != nil {
+		return fallback
+	}
+	switch {
+	case strings.HasPrefix(u.Scheme, "syslog"):
+		return "syslog"
+	case strings.HasPrefix(u.Scheme, "gelf"):
+		return "gelf"
+	}
+	return fallback
+}
+
+// redactCore applies the redaction rules rc, if any, to core.
+func redactCore(core zapcore.Core, rc *redact.Config) (zapcore.Core, error) {
+	if rc == nil {
+		return core, nil
+	}
+	r, err := redact.New(*rc)
+	if err != nil {
+		return nil, err
+	}
+	return redact.NewCore(core, r), nil
+}
+
+// chain links the lines written to out, continuing the chain already in the
+// file at path, if any.
+func chain(path, encoding string, cc sink.ChainConfig, out zapcore.WriteSyncer) (zapcore.WriteSyncer, error) {
+	if cc.Format == "" {
+		cc.Format = encoding
+	}
+	var pos sink.ChainPosition
+	if file, ok := filePath(path); ok {
+		var err error
+		if pos, err = sink.ChainEnd(file, cc); err != nil {
+			return nil, err
+		}
+	}
+	return sink.NewChainWriter(out, cc, pos)
+}
+
+func (s *sinkSet) buildWebhookCore(gconfig GLoggerConfig, wc sink.WebhookConfig) (zapcore.Core, error) {
+	min := wc.MinLevel
+	if min == "" {
+		min = "error"
+	}
+	enabler, err := levelRange(min, "", gconfig.Level)
+	if err != nil {
+		return nil, err
+	}
+	w, err := sink.NewWebhook(wc, s.errOut)
+	if err != nil {
+		return nil, err
+	}
+	s.closers = append(s.closers, func() { w.Close() })
+	return redactCore(sink.NewWebhookCore(zapcore.NewJSONEncoder(config.EncoderConfig), w, enabler), gconfig.Redact)
+}
+
+func (s *sinkSet) buildShipperCore(gconfig GLoggerConfig, sc sink.ShipperConfig) (zapcore.Core, error) {
+	enabler, err := levelRange(sc.MinLevel, "", gconfig.Level)
+	if err != nil {
+		return nil, err
+	}
+	if sc.Format == "loki" && len(sc.Labels) == 0 && gconfig.Service != "" {
+		sc.Labels = map[string]string{"service": gconfig.Service}
+	}
+	sh, err := sink.NewShipper(sc, s.errOut)
+	if err != nil {
+		return nil, err
+	}
+	s.closers = append(s.closers, func() { sh.Close() })
+	return redactCore(sink.NewShipperCore(zapcore.NewJSONEncoder(config.EncoderConfig), sh, enabler), gconfig.Redact)
+}
+
+func (s *sinkSet) newCore(gconfig GLoggerConfig, enc zapcore.Encoder, out zapcore.WriteSyncer, enab zapcore.LevelEnabler) zapcore.Core {
+	if gconfig.Async == nil {
+		return zapcore.NewCore(enc, out, enab)
+	}
+	w := sink.NewAsyncWriter(out, *gconfig.Async)
+	s.async = append(s.async, w)
+	return sink.NewAsyncCore(enc, w, enab)
+}
+
+// close drains the async queues and releases every output. Only the first
+// call does any work.
+func (s *sinkSet) close() error {
+	s.once.Do(func() {
+		if s.top != nil {
+			// Flush what the filters hold back before their summaries stop.
+			s.top.Sync()
+		}
+		close(s.stop)
+		s.wg.Wait()
+		if s.core != nil {
+			s.err = s.core.Sync()
+		}
+		for _, w := range s.async {
+			if err := w.Close(); err != nil && s.err == nil {
+				s.err = err
+			}
+		}
+		if s.audit != nil {
+			if err := s.audit.out.Close(); err != nil && s.err == nil {
+				s.err = err
+			}
+		}
+		for _, fn := range s.closers {
+			fn()
+		}
+	})
+	return s.err
+}
+
+// reportf writes an internal error to the logger's error output, the same
+// way zap reports failed writes.
+func (s *sinkSet) reportf(format string, args ...interface{}) {
+	if s.errOut == nil {
+		return
+	}
+	fmt.Fprintf(s.errOut, "%v "+format+"\n", append([]interface{}{time.Now()}, args...)...)
+	s.errOut.Sync()
+}
+
+// reopen swaps every file output for a fresh handle on the same path.
+func (s *sinkSet) reopen() error {
+	var first error
+	for _, f := range s.files {
+		if err := f.Reopen(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (s *sinkSet) dropped() uint64 {
+	var n uint64
+	for _, w := range s.async {
+		n += w.Dropped()
+	}
+	return n
+}
+
+func levelRange(min, max string, level zapcore.Level) (zapcore.LevelEnabler, error) {
+	lo, hi := level, zapcore.FatalLevel
+	if min != "" {
+		if err := lo.UnmarshalText([]byte(min)); err != nil {
+			return nil, err
+		}
+	}
+	if max != "" {
+		if err := hi.UnmarshalText([]byte(max)); err != nil {
+			return nil, err
+		}
+	}
+	if lo > hi {
+		return nil, fmt.Errorf("min level %s is above max level %s", lo, hi)
+	}
+	return zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l >= lo && l <= hi
+	}), nil
+}
+
+func newEncoder(gconfig GLoggerConfig, encoding string) (zapcore.Encoder, error) {
+	cfg := config.EncoderConfig
+	switch encoding {
+	case "kvpare":
+		return encoder.NewKVEncoder(cfg), nil
+	case "json":
+		return zapcore.NewJSONEncoder(cfg), nil
+	case "console":
+		return zapcore.NewConsoleEncoder(cfg), nil
+	case "syslog":
+		// The syslog header already carries the time and severity.
+		cfg.TimeKey, cfg.LevelKey = "", ""
+		sc := encoder.SyslogConfig{SDKeys: contextKeys}
+		if gconfig.Syslog != nil {
+			sc = *gconfig.Syslog
+			if sc.SDKeys == nil {
+				sc.SDKeys = contextKeys
+			}
+		}
+		return encoder.NewSyslogEncoder(sc, encoder.NewKVEncoder(cfg))
+	case "gelf":
+		var gc encoder.GELFConfig
+		if gconfig.GELF != nil {
+			gc = *gconfig.GELF
+		}
+		return encoder.NewGELFEncoder(gc), nil
+	}
+	return nil, fmt.Errorf("unknown encoding %q", encoding)
+}
+
+// open behaves like zap.Open but creates the parent directory of plain file
+// paths first and opens them as reopenable files. The outputs are released
+// by close.
+func (s *sinkSet) open(paths ...string) (zapcore.WriteSyncer, error) {
+	writers := make([]zapcore.WriteSyncer, 0, len(paths))
+	for _, p := range paths {
+		ws, err := s.openPath(p)
+		if err != nil {
+			return nil, err
+		}
+		writers = append(writers, ws)
+	}
+	return zap.CombineWriteSyncers(writers...), nil
+}
+
+func (s *sinkSet) openPath(p string) (zapcore.WriteSyncer, error) {
+	ws, err := s.openWriter(p)
+	if err != nil {
+		return nil, err
+	}
+	counted := metrics.NewCountingWriter(ws)
+	s.written = append(s.written, sinkBytes{path: p, w: counted})
+	return counted, nil
+}
+
+func (s *sinkSet) openWriter(p string) (zapcore.WriteSyncer, error) {
+	if file, ok := filePath(p); ok {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return nil, err
+		}
+		f, err := sink.OpenFile(file)
+		if err != nil {
+			return nil, err
+		}
+		s.files = append(s.files, f)
+		s.closers = append(s.closers, func() { f.Close() })
+		return f, nil
+	}
+	ws, closeFn, err := zap.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	s.closers = append(s.closers, closeFn)
+	if p == "stdout" || p == "stderr" {
+		return stdSyncer{ws}, nil
+	}
+	return ws, nil
+}
+
+// stdSyncer skips syncing stdout and stderr, which fails when they are pipes
+// or terminals.
+type stdSyncer struct {
+	zapcore.WriteSyncer
+}
+
+func (stdSyncer) Sync() error {
+	return nil
+}
+
+// filePath reports the local file behind an output path, if any.
+func filePath(p string) (string, bool) {
+	if p == "stdout" || p == "stderr" {
+		return "", false
+	}
+	u, err := url.Parse(p)
+	if err != nil || u.Scheme == "" {
+		return p, true
+	}
+	if u.Scheme == "file" {
+		return u.Path, true
+	}
+	return "", false
+}
@@ -0,0 +1,110 @@
+package test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/sink"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// gateWriter blocks every write until the gate is opened.
+type gateWriter struct {
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	gate chan struct{}
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gateWriter) Sync() error { return nil }
+
+func (w *gateWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriterDropNewest(t *testing.T) {
+	out := &gateWriter{gate: make(chan struct{})}
+	w := sink.NewAsyncWriter(out, sink.AsyncConfig{
+		QueueSize:     2,
+		FlushInterval: time.Hour,
+		Overflow:      sink.DropNewest,
+	})
+	// The first entry is picked up by the flusher, which then blocks on the
+	// gate; the next two fill the queue and the last one overflows.
+	w.WriteLevel(zapcore.InfoLevel, []byte("a\n"))
+	w.WriteLevel(zapcore.InfoLevel, []byte("b\n"))
+	time.Sleep(50 * time.Millisecond)
+	w.WriteLevel(zapcore.InfoLevel, []byte("c\n"))
+	w.WriteLevel(zapcore.InfoLevel, []byte("d\n"))
+	if ok := w.WriteLevel(zapcore.InfoLevel, []byte("e\n")); ok {
+		t.Errorf("expected the overflowing entry to be dropped")
+	}
+	close(out.gate)
+	w.Sync()
+	if w.Dropped() != 1 {
+		t.Errorf("dropped = %d, want 1", w.Dropped())
+	}
+	if got := out.String(); got != "a\nb\nc\nd\n" {
+		t.Errorf("written = %q", got)
+	}
+}
+
+func TestAsyncWriterDropBelowLevel(t *testing.T) {
+	out := &gateWriter{gate: make(chan struct{})}
+	w := sink.NewAsyncWriter(out, sink.AsyncConfig{
+		QueueSize:     1,
+		FlushInterval: time.Hour,
+		Overflow:      sink.DropBelowLevel,
+		DropLevel:     zapcore.WarnLevel,
+	})
+	w.WriteLevel(zapcore.InfoLevel, []byte("a\n"))
+	time.Sleep(50 * time.Millisecond)
+	w.WriteLevel(zapcore.InfoLevel, []byte("b\n"))
+	if ok := w.WriteLevel(zapcore.DebugLevel, []byte("c\n")); ok {
+		t.Errorf("expected the debug entry to be dropped")
+	}
+	accepted := make(chan bool)
+	go func() { accepted <- w.WriteLevel(zapcore.ErrorLevel, []byte("d\n")) }()
+	close(out.gate)
+	if !<-accepted {
+		t.Errorf("expected the error entry to block until accepted")
+	}
+	w.Sync()
+	if got := out.String(); got != "a\nb\nd\n" {
+		t.Errorf("written = %q", got)
+	}
+}
+
+func TestGLoggerAsyncSync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "async.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: path,
+		Async:      &sink.AsyncConfig{FlushInterval: time.Hour},
+	})
+	log.Info("queued entry")
+	if err := log.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "queued entry") {
+		t.Errorf("entry not drained by Sync: %s", data)
+	}
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/filter"
+	"github.com/MSLibs/glogger/core/sink"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestGLoggerAudit(t *testing.T) {
+	dir := t.TempDir()
+	appPath := filepath.Join(dir, "app.log")
+	auditPath := filepath.Join(dir, "audit", "audit.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: appPath,
+		Level:      zapcore.ErrorLevel,
+		Sampling:   &filter.SamplingConfig{First: 1, Thereafter: 1000},
+		RateLimit:  &filter.RateLimitConfig{Rate: 1},
+		Audit:      &glogger.AuditConfig{Path: auditPath},
+	})
+	ctx := context.WithValue(context.Background(), glogger.UserFlag, "alice")
+	ctx = context.WithValue(ctx, glogger.SourceIP, "10.0.0.7")
+	ctx = context.WithValue(ctx, glogger.RequestID, "req-1")
+	for i := 0; i < 5; i++ {
+		if err := log.Audit(&ctx, "user.delete", "user/42"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	log.Audit(&ctx, "role.grant", "user/43", zap.String(glogger.Outcome, "denied"))
+
+	// Entries are synced as they are written, before Close.
+	lines := strings.Split(strings.TrimSpace(readFile(t, auditPath)), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("audit has %d entries, want 6:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[5]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		glogger.Action:    "role.grant",
+		glogger.Target:    "user/43",
+		glogger.Actor:     "alice",
+		glogger.SourceIP:  "10.0.0.7",
+		glogger.RequestID: "req-1",
+		glogger.Outcome:   "denied",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %q", k, entry[k], v)
+		}
+	}
+	if !strings.Contains(lines[0], `"outcome":"success"`) {
+		t.Errorf("default outcome missing: %s", lines[0])
+	}
+
+	log.Close(context.Background())
+	if strings.Contains(readFile(t, appPath), "user.delete") {
+		t.Errorf("audit entries leaked into the application log")
+	}
+}
+
+func TestGLoggerAuditWithoutChannel(t *testing.T) {
+	dir := t.TempDir()
+	appPath := filepath.Join(dir, "app.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: appPath,
+		Sampling:   &filter.SamplingConfig{First: 1, Thereafter: 1000},
+	})
+	ctx := context.Background()
+	if err := log.Audit(&ctx, "user.delete", "user/42"); err != glogger.ErrNoAudit {
+		t.Errorf("Audit without a channel returned %v, want ErrNoAudit", err)
+	}
+	log.Close(context.Background())
+	if strings.Contains(readFile(t, appPath), "user.delete") {
+		t.Errorf("audit entry written to the application log")
+	}
+}
+
+func TestGLoggerAuditReturnsWriteError(t *testing.T) {
+	// Every write to /dev/full fails with ENOSPC.
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("no /dev/full")
+	}
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: filepath.Join(t.TempDir(), "app.log"),
+		Audit:      &glogger.AuditConfig{Path: "/dev/full"},
+	})
+	defer log.Close(context.Background())
+	ctx := context.Background()
+	if err := log.Audit(&ctx, "user.delete", "user/42"); err == nil {
+		t.Error("Audit returned nil for a failed write")
+	}
+}
+
+type failingSyncer struct{}
+
+func (failingSyncer) Write(p []byte) (int, error) { return len(p), nil }
+func (failingSyncer) Sync() error                 { return errors.New("sync failed") }
+
+func TestDurableWriterReportsBackgroundSyncError(t *testing.T) {
+	w := sink.NewDurableWriter(failingSyncer{}, 10, time.Millisecond)
+	if _, err := w.Write([]byte("first\n")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, err := w.Write([]byte("second\n")); err == nil {
+		t.Error("the failed background sync was not returned by the next write")
+	}
+}
@@ -0,0 +1,126 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/sink"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestAuditHashChain(t *testing.T) {
+	dir := t.TempDir()
+	auditPath := filepath.Join(dir, "audit.log")
+	chain := &sink.ChainConfig{Key: []byte("secret"), CheckpointEvery: 2}
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: filepath.Join(dir, "app.log"),
+		Audit:      &glogger.AuditConfig{Path: auditPath, Chain: chain},
+	})
+	ctx := context.WithValue(context.Background(), glogger.UserFlag, "alice")
+	for _, target := range []string{"doc/1", "doc/2", "doc/3"} {
+		log.Audit(&ctx, "doc.read", target)
+	}
+	log.Close(context.Background())
+
+	verify := sink.ChainConfig{Format: "json", Key: chain.Key}
+	if err := sink.VerifyChainFile(auditPath, verify); err != nil {
+		t.Fatal(err)
+	}
+	data := readFile(t, auditPath)
+	lines := strings.Split(strings.TrimSpace(data), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[2], sink.CheckpointMessage) {
+		t.Fatalf("want 3 entries and a checkpoint:\n%s", data)
+	}
+	if err := sink.VerifyChainFile(auditPath, sink.ChainConfig{Format: "json", Key: []byte("guess")}); err == nil {
+		t.Errorf("chain verified with the wrong key")
+	}
+
+	tampered := strings.Replace(data, "doc/2", "doc/9", 1)
+	assertBrokenAt(t, tampered, verify, 2)
+	removed := strings.Join(append([]string{lines[0]}, lines[2:]...), "\n")
+	assertBrokenAt(t, removed, verify, 2)
+}
+
+func TestSinkHashChainResumes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chained.log")
+	for i := 0; i < 2; i++ {
+		log := glogger.CreateLog(glogger.GLoggerConfig{
+			OutputPath: filepath.Join(dir, "app.log"),
+			Sinks: []glogger.SinkConfig{{
+				Path:  path,
+				Chain: &sink.ChainConfig{CheckpointEvery: 3},
+			}},
+		})
+		log.Info("run", zap.Int("n", i))
+		log.Close(context.Background())
+	}
+
+	if err := sink.VerifyChainFile(path, sink.ChainConfig{Format: "kvpare"}); err != nil {
+		t.Fatal(err)
+	}
+	// Two entries per run, and a checkpoint counting across the restart.
+	if n := strings.Count(readFile(t, path), "prev_hash?="); n != 5 {
+		t.Errorf("%d chained lines, want 5", n)
+	}
+}
+
+func assertBrokenAt(t *testing.T, data string, cfg sink.ChainConfig, line int) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "broken.log")
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := sink.VerifyChainFile(path, cfg)
+	ce, ok := err.(*sink.ChainError)
+	if !ok || ce.Line != line {
+		t.Errorf("VerifyChainFile = %v, want a break at line %d", err, line)
+	}
+}
+
+func TestVerifyChainAnchorsStartAndCheckpoints(t *testing.T) {
+	cfg := sink.ChainConfig{Format: "json", CheckpointEvery: 2}
+	var buf bytes.Buffer
+	w, err := sink.NewChainWriter(zapcore.AddSync(&buf), cfg, sink.ChainPosition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 7; i++ {
+		fmt.Fprintf(w, "{\"msg\":\"entry %d\"}\n", i)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 10 {
+		t.Fatalf("want 7 entries and 3 checkpoints:\n%s", buf.String())
+	}
+
+	// Lines removed from the head no longer link to the genesis hash.
+	assertBrokenAt(t, strings.Join(lines[4:], "\n"), cfg, 1)
+
+	// Rotated files verify as a sequence, but not on their own.
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	ioutil.WriteFile(first, []byte(strings.Join(lines[:4], "\n")+"\n"), 0644)
+	ioutil.WriteFile(second, []byte(strings.Join(lines[4:], "\n")+"\n"), 0644)
+	if err := sink.VerifyChainFiles(cfg, first, second); err != nil {
+		t.Errorf("rotated files: %v", err)
+	}
+	if err := sink.VerifyChainFiles(cfg, second); err == nil {
+		t.Errorf("continued file verified without its predecessor")
+	}
+
+	// A checkpoint count that disagrees with the entries seen is reported.
+	buf.Reset()
+	w, _ = sink.NewChainWriter(zapcore.AddSync(&buf), cfg, sink.ChainPosition{Entries: 5})
+	for i := 0; i < 2; i++ {
+		fmt.Fprintf(w, "{\"msg\":\"entry %d\"}\n", i)
+	}
+	assertBrokenAt(t, buf.String(), cfg, 2)
+}
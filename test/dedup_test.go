@@ -0,0 +1,82 @@
+package test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/filter"
+)
+
+func TestGLoggerDedup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: path,
+		Dedup:      &filter.DedupConfig{Window: time.Hour},
+	})
+	for i := 0; i < 5; i++ {
+		log.Error("connection refused")
+	}
+	for i := 0; i < 2; i++ {
+		log.Info("recovered")
+	}
+	log.Close(context.Background())
+
+	data := readFile(t, path)
+	if n := strings.Count(data, "connection refused"); n != 1 {
+		t.Errorf("wrote %d lines for the run, want 1: %s", n, data)
+	}
+	if !strings.Contains(data, "repeated?=5#") {
+		t.Errorf("run count missing: %s", data)
+	}
+	if !strings.Contains(data, "repeated?=2#") {
+		t.Errorf("pending run not flushed on close: %s", data)
+	}
+	if strings.Index(data, "repeated?=5#") > strings.Index(data, `"recovered"`) {
+		t.Errorf("run written after the next entry: %s", data)
+	}
+}
+
+func TestGLoggerDedupSugared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: path,
+		Dedup:      &filter.DedupConfig{Window: time.Hour},
+	})
+	for i := 0; i < 5; i++ {
+		log.Infof("retrying %s", "db")
+	}
+	log.Close(context.Background())
+
+	data := readFile(t, path)
+	if n := strings.Count(data, "retrying db"); n != 1 {
+		t.Errorf("wrote %d lines for the run, want 1: %s", n, data)
+	}
+	if !strings.Contains(data, "repeated?=5#") {
+		t.Errorf("run count missing: %s", data)
+	}
+}
+
+func TestGLoggerDedupWindow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: path,
+		Dedup:      &filter.DedupConfig{Window: 50 * time.Millisecond},
+	})
+	defer log.Close(context.Background())
+	log.Info("disk almost full")
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(readFile(t, path), "disk almost full") {
+		if time.Now().After(deadline) {
+			t.Fatal("held-back entry not written once its window expired")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if data := readFile(t, path); strings.Contains(data, "repeated?=") {
+		t.Errorf("single entry carries a repeat count: %s", data)
+	}
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/filter"
+)
+
+func TestGLoggerSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sampled.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: path,
+		Sampling:   &filter.SamplingConfig{First: 2, Thereafter: 1000},
+	})
+	for i := 0; i < 10; i++ {
+		log.Error("hot loop failure")
+	}
+	log.Info("other message")
+	log.Close(context.Background())
+
+	data := readFile(t, path)
+	if n := strings.Count(data, "hot loop failure"); n != 2 {
+		t.Errorf("kept %d sampled entries, want 2", n)
+	}
+	if !strings.Contains(data, "other message") {
+		t.Errorf("unrelated message was sampled out")
+	}
+	if !strings.Contains(data, filter.SummaryMessage) || !strings.Contains(data, "sampled?=8#") {
+		t.Errorf("summary missing: %s", data)
+	}
+}
+
+func TestGLoggerRateLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limited.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: path,
+		RateLimit:  &filter.RateLimitConfig{Rate: 0.001, Burst: 4},
+	})
+	// CreateLog's own "logger construction succeeded" takes the first token.
+	for i := 0; i < 10; i++ {
+		log.Info("burst entry")
+	}
+	log.Close(context.Background())
+
+	data := readFile(t, path)
+	if n := strings.Count(data, "burst entry"); n != 3 {
+		t.Errorf("kept %d entries, want 3", n)
+	}
+	if !strings.Contains(data, "ratelimited?=7#") {
+		t.Errorf("summary missing: %s", data)
+	}
+}
@@ -0,0 +1,168 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/encoder"
+	"github.com/MSLibs/glogger/core/sink"
+)
+
+func listenUDP(t *testing.T) net.PacketConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	return conn
+}
+
+func TestGLoggerGELFSink(t *testing.T) {
+	conn := listenUDP(t)
+	defer conn.Close()
+
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: filepath.Join(t.TempDir(), "app.log"),
+		Sinks:      []glogger.SinkConfig{{Path: "gelf+udp://" + conn.LocalAddr().String() + "?compress=gzip", MinLevel: "error"}},
+		GELF:       &encoder.GELFConfig{Host: "web-1", Fields: map[string]interface{}{"service": "orders"}},
+	})
+	ctx := context.WithValue(context.Background(), glogger.RequestID, "req-1")
+	ctx = context.WithValue(ctx, glogger.PlatformID, "PC")
+	log.WithError(&ctx, "charge failed")
+
+	buf := make([]byte, 64<<10)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(buf[:n]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(zr)
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("%v: %s", err, data)
+	}
+	for k, want := range map[string]interface{}{
+		"version":       "1.1",
+		"host":          "web-1",
+		"short_message": "charge failed",
+		"level":         float64(3),
+		"_requestId":    "req-1",
+		"_platformId":   "PC",
+		"_service":      "orders",
+	} {
+		if doc[k] != want {
+			t.Errorf("%s = %v, want %v", k, doc[k], want)
+		}
+	}
+	if _, ok := doc["full_message"].(string); !ok {
+		t.Errorf("stack trace missing from full_message: %s", data)
+	}
+}
+
+func TestGLoggerGELFOutputPath(t *testing.T) {
+	conn := listenUDP(t)
+	defer conn.Close()
+
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: "gelf+udp://" + conn.LocalAddr().String(),
+		GELF:       &encoder.GELFConfig{Host: "web-1"},
+	})
+	log.Info("started")
+	log.Close(context.Background())
+
+	buf := make([]byte, 64<<10)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(buf[:n], &doc); err != nil {
+			t.Fatalf("%v: %s", err, buf[:n])
+		}
+		if doc["short_message"] != "started" {
+			continue
+		}
+		if doc["version"] != "1.1" || doc["host"] != "web-1" {
+			t.Errorf("GELF message = %s", buf[:n])
+		}
+		return
+	}
+}
+
+func TestGELFWriterChunking(t *testing.T) {
+	conn := listenUDP(t)
+	defer conn.Close()
+
+	w, err := sink.NewGELFWriter(sink.GELFConfig{Address: conn.LocalAddr().String(), ChunkSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	msg := `{"short_message":"` + strings.Repeat("x", 400) + `"}`
+	if _, err := w.Write([]byte(msg + "\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	var payload []byte
+	var id []byte
+	for i := 0; i < 5; i++ {
+		buf := make([]byte, 200)
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := buf[:n]
+		if n > 100 || c[0] != 0x1e || c[1] != 0x0f || int(c[10]) != i || c[11] != 5 {
+			t.Fatalf("bad chunk %d header % x", i, c[:12])
+		}
+		if id == nil {
+			id = c[2:10]
+		} else if !bytes.Equal(id, c[2:10]) {
+			t.Fatalf("chunk %d has a different message id", i)
+		}
+		payload = append(payload, c[12:]...)
+	}
+	if string(payload) != msg {
+		t.Errorf("reassembled %q", payload)
+	}
+}
+
+func TestGELFWriterSplitsDocuments(t *testing.T) {
+	conn := listenUDP(t)
+	defer conn.Close()
+
+	w, err := sink.NewGELFWriter(sink.GELFConfig{Address: conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	// An async flush may hand several encoded entries to one Write.
+	if _, err := w.Write([]byte(`{"short_message":"a"}` + "\n" + `{"short_message":"b"}` + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64<<10)
+	for _, want := range []string{"a", "b"} {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(buf[:n], &doc); err != nil || doc["short_message"] != want {
+			t.Errorf("datagram = %q, want document %q", buf[:n], want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/handler"
+)
+
+// serveContext runs req through mw and returns the context the next handler
+// saw.
+func serveContext(t *testing.T, mw func(http.Handler) http.Handler, req *http.Request) context.Context {
+	t.Helper()
+	var ctx context.Context
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})
+	mw(next).ServeHTTP(httptest.NewRecorder(), req)
+	if ctx == nil {
+		t.Fatal("next handler not called")
+	}
+	return ctx
+}
+
+func TestLogRequestHandlerSanitizesQuery(t *testing.T) {
+	req := httptest.NewRequest("GET", "/pay?order=7&access_token=abc&Sign=x1", nil)
+	req.Header.Set("Referer", "https://shop.example.com/login?user=bob&password=hunter2#top")
+	ctx := serveContext(t, handler.LogRequestHandler, req)
+
+	if got, want := ctx.Value(glogger.Url), "/pay?order=7&access_token=***&Sign=***"; got != want {
+		t.Errorf("url = %v, want %v", got, want)
+	}
+	if got, want := ctx.Value(glogger.Referer), "https://shop.example.com/login?user=bob&password=***#top"; got != want {
+		t.Errorf("referer = %v, want %v", got, want)
+	}
+}
+
+func TestSanitizerAllowList(t *testing.T) {
+	s := handler.NewSanitizer(handler.SanitizeConfig{AllowParams: []string{"page"}})
+	if got, want := s.URL("/list?page=2&q=secret&flag"), "/list?page=2&q=***&flag=***"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+	if got := s.Header("cookie", "sid=1"); got != "***" {
+		t.Errorf("cookie header = %q", got)
+	}
+	if got := s.Header("X-Platform", "ios"); got != "ios" {
+		t.Errorf("X-Platform header = %q", got)
+	}
+}
+
+func TestLogRequestHandlerCapturesHeaders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{OutputPath: path})
+	defer log.Close(context.Background())
+
+	mw := handler.LogRequestHandlerWith(handler.RequestLogConfig{
+		RequestHeaders:  []string{"X-Platform", "authorization", "X-Missing"},
+		ResponseHeaders: []string{"Content-Type", "Set-Cookie"},
+		AccessLog:       true,
+	})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		glogger.WithContext(&ctx).Info("handling")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "sid=secret")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"ok":true}`))
+	})
+	req := httptest.NewRequest("POST", "/orders", nil)
+	req.Header.Set("X-Platform", "ios")
+	req.Header.Set("Authorization", "Bearer abc")
+	mw(next).ServeHTTP(httptest.NewRecorder(), req)
+	log.Sync()
+
+	data := readFile(t, path)
+	for _, want := range []string{
+		"req.Authorization?=***#", "req.X-Platform?=ios#",
+		"status?=201#", "bytes?=11#", "resp.Content-Type?=application/json#", "resp.Set-Cookie?=***#",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("%q missing:\n%s", want, data)
+		}
+	}
+	if strings.Contains(data, "Bearer abc") || strings.Contains(data, "sid=secret") || strings.Contains(data, "X-Missing") {
+		t.Errorf("sensitive or absent header logged:\n%s", data)
+	}
+	if n := strings.Count(data, "req.X-Platform?=ios#"); n != 2 {
+		t.Errorf("X-Platform on %d entries, want 2", n)
+	}
+}
+
+func TestLogRequestHandlerCapturesBodies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "body.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{OutputPath: path})
+	defer log.Close(context.Background())
+
+	mw := handler.LogRequestHandlerWith(handler.RequestLogConfig{
+		Body: &handler.BodyCaptureConfig{MaxBytes: 64},
+	})
+	var seen string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		seen = string(body)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"token":"t-123","items":["` + strings.Repeat("x", 100) + `"]}`))
+	})
+	reqBody := `{"user":"bob","password":"hunter2","phone":"18512343365"}`
+	req := httptest.NewRequest("POST", "/login", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	mw(next).ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest("POST", "/upload", strings.NewReader("\x89PNG binary"))
+	req.Header.Set("Content-Type", "image/png")
+	mw(next).ServeHTTP(httptest.NewRecorder(), req)
+	log.Sync()
+
+	if seen != "\x89PNG binary" {
+		t.Errorf("next handler read %q", seen)
+	}
+	data := readFile(t, path)
+	for _, want := range []string{`\"password\":\"***\"`, `\"phone\":\"185****3365\"`, `\"token\":\"***\"`, "respBodyTruncated?=true#"} {
+		if !strings.Contains(data, want) {
+			t.Errorf("%q missing:\n%s", want, data)
+		}
+	}
+	for _, leaked := range []string{"hunter2", "t-123", "PNG"} {
+		if strings.Contains(data, leaked) {
+			t.Errorf("%q logged:\n%s", leaked, data)
+		}
+	}
+	if n := strings.Count(data, "reqBody?="); n != 1 {
+		t.Errorf("%d request bodies logged, want 1", n)
+	}
+}
+
+func TestLogRequestHandlerTrustedProxies(t *testing.T) {
+	mw := handler.LogRequestHandlerWith(handler.RequestLogConfig{
+		TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1"},
+	})
+	for _, tt := range []struct {
+		name, remote string
+		header       http.Header
+		want         string
+	}{
+		{"untrusted peer", "203.0.113.9:5000", http.Header{"X-Forwarded-For": {"1.2.3.4"}}, "203.0.113.9"},
+		{"no headers", "10.1.1.1:5000", nil, "10.1.1.1"},
+		{"xff", "192.0.2.1:5000", http.Header{"X-Forwarded-For": {"6.6.6.6, 1.2.3.4, 10.0.0.5"}}, "1.2.3.4"},
+		{"all trusted", "10.1.1.1:5000", http.Header{"X-Forwarded-For": {"10.0.0.7, 10.0.0.5"}}, "10.0.0.7"},
+		{"real ip", "10.1.1.1:5000", http.Header{"X-Real-Ip": {"1.2.3.4"}}, "1.2.3.4"},
+		{"real ip not an address", "10.1.1.1:5000", http.Header{"X-Real-Ip": {"<script>"}}, "10.1.1.1"},
+		{"xff not an address", "10.1.1.1:5000", http.Header{"X-Forwarded-For": {"1.2.3.4, evil, 10.0.0.5"}}, "10.0.0.5"},
+		{"forwarded unknown", "10.1.1.1:5000", http.Header{"Forwarded": {"for=unknown"}}, "10.1.1.1"},
+		{"forwarded obfuscated", "10.1.1.1:5000", http.Header{"Forwarded": {`for=1.2.3.4, for="_hidden"`}}, "10.1.1.1"},
+		{"forwarded", "10.1.1.1:5000", http.Header{
+			"Forwarded":       {`for=6.6.6.6, for=198.51.100.17;proto=https, for="10.0.0.5:8080"`},
+			"X-Forwarded-For": {"7.7.7.7"},
+		}, "198.51.100.17"},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tt.remote
+		for k, v := range tt.header {
+			req.Header[k] = v
+		}
+		ctx := serveContext(t, mw, req)
+		if got := ctx.Value(glogger.SourceIP); got != tt.want {
+			t.Errorf("%s: sourceip = %v, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// hijackServer serves mw(next) where next hijacks the connection and answers
+// on it directly, and returns the response body.
+func hijackServer(t *testing.T, mw func(http.Handler) http.Handler) string {
+	t.Helper()
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "not a hijacker", http.StatusInternalServerError)
+			return
+		}
+		conn, buf, err := hj.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		buf.Flush()
+	})
+	server := httptest.NewServer(mw(next))
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestLogRequestHandlerHijack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{OutputPath: path})
+	defer log.Close(context.Background())
+
+	mw := handler.LogRequestHandlerWith(handler.RequestLogConfig{
+		AccessLog: true,
+		Body:      &handler.BodyCaptureConfig{},
+	})
+	if body := hijackServer(t, mw); body != "hijacked" {
+		t.Errorf("body = %q, want the hijacked response", body)
+	}
+}
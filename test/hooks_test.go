@@ -0,0 +1,57 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/MSLibs/glogger"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestGLoggerHooks(t *testing.T) {
+	dir := t.TempDir()
+	log := glogger.CreateLog(glogger.GLoggerConfig{OutputPath: filepath.Join(dir, "hooks.log")})
+
+	var mu sync.Mutex
+	var paged []string
+	log.AddHook(func(ent zapcore.Entry, fields []zap.Field) error {
+		for _, f := range fields {
+			if f.Key == glogger.RequestID {
+				mu.Lock()
+				paged = append(paged, ent.Message+"/"+f.String)
+				mu.Unlock()
+			}
+		}
+		return nil
+	}, zapcore.ErrorLevel)
+	log.AddHook(func(zapcore.Entry, []zap.Field) error {
+		panic("broken hook")
+	}, nil)
+	log.AddHook(func(zapcore.Entry, []zap.Field) error {
+		return errors.New("failing hook")
+	}, nil)
+	var async []string
+	log.AddAsyncHook(func(ent zapcore.Entry, _ []zap.Field) error {
+		async = append(async, ent.Message)
+		return nil
+	}, nil, 16)
+
+	ctx := context.WithValue(context.Background(), glogger.RequestID, "req-1")
+	log.WithInfo(&ctx, "all good")
+	log.WithError(&ctx, "db down")
+	log.Close(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paged) != 1 || paged[0] != "db down/req-1" {
+		t.Errorf("error hook saw %v", paged)
+	}
+	if len(async) != 2 || async[0] != "all good" || async[1] != "db down" {
+		t.Errorf("async hook saw %v", async)
+	}
+}
@@ -0,0 +1,95 @@
+package test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/utils"
+)
+
+func TestGLoggerMetadataFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath:  path,
+		Service:     "orders",
+		Version:     "1.4.2",
+		Environment: "staging",
+	})
+	log.Info("first")
+	log.Info("second")
+	log.Close(context.Background())
+
+	host, _ := os.Hostname()
+	want := []string{
+		"service?=orders#", "version?=1.4.2#", "env?=staging#",
+		"hostname?=" + host + "#", "pid?=" + strconv.Itoa(os.Getpid()) + "#",
+	}
+	for _, line := range strings.Split(strings.TrimSpace(readFile(t, path)), "\n") {
+		for _, w := range want {
+			if !strings.Contains(line, w) {
+				t.Errorf("%q missing from %s", w, line)
+			}
+		}
+	}
+}
+
+func TestContainerIDFrom(t *testing.T) {
+	id := strings.Repeat("3f9a", 16)
+	for _, cgroup := range []string{
+		"12:pids:/docker/" + id + "\n0::/\n",
+		"0::/kubepods.slice/kubepods-burstable.slice/cri-containerd-" + id + ".scope\n",
+	} {
+		if got := utils.ContainerIDFrom(strings.NewReader(cgroup)); got != id {
+			t.Errorf("ContainerIDFrom(%q) = %q", cgroup, got)
+		}
+	}
+	if got := utils.ContainerIDFrom(strings.NewReader("0::/user.slice/session-2.scope\n")); got != "" {
+		t.Errorf("host cgroup yielded %q", got)
+	}
+}
+
+func TestGLoggerKubernetesFields(t *testing.T) {
+	dir := t.TempDir()
+	podinfo := filepath.Join(dir, "podinfo")
+	os.Mkdir(podinfo, 0755)
+	ioutil.WriteFile(filepath.Join(podinfo, "namespace"), []byte("payments\n"), 0644)
+	ioutil.WriteFile(filepath.Join(podinfo, "name"), []byte("orders-7d9f-x2"), 0644)
+	ioutil.WriteFile(filepath.Join(podinfo, "labels"), []byte("app=\"orders\"\ntier=\"backend\"\n"), 0644)
+	os.Setenv("NODE_NAME", "node-3")
+	defer os.Unsetenv("NODE_NAME")
+
+	path := filepath.Join(dir, "app.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: path,
+		Kubernetes: glogger.KubernetesConfig{
+			PodInfoDir: podinfo,
+			Labels:     []string{"app", "missing"},
+			PodKey:     "pod",
+		},
+	})
+	log.Info("hello")
+	log.Close(context.Background())
+
+	data := readFile(t, path)
+	for _, want := range []string{"k8s.namespace?=payments#", "pod?=orders-7d9f-x2#", "k8s.node?=node-3#", "k8s.label.app?=orders#"} {
+		if !strings.Contains(data, want) {
+			t.Errorf("%q missing: %s", want, data)
+		}
+	}
+	if strings.Contains(data, "tier") || strings.Contains(data, "missing") {
+		t.Errorf("unlisted label logged: %s", data)
+	}
+}
+
+func TestReadPodInfoOutsideKubernetes(t *testing.T) {
+	info := utils.ReadPodInfo(filepath.Join(t.TempDir(), "absent"))
+	if info.Namespace != "" || info.Pod != "" || len(info.Labels) != 0 {
+		t.Errorf("ReadPodInfo = %+v, want empty", info)
+	}
+}
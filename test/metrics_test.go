@@ -0,0 +1,60 @@
+package test
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/filter"
+	"github.com/MSLibs/glogger/core/handler"
+	"github.com/MSLibs/glogger/core/metrics"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: path,
+		Sampling:   &filter.SamplingConfig{First: 1, Thereafter: 1000},
+	})
+	log.Error("metered failure")
+	log.Error("metered failure")
+
+	rec := httptest.NewRecorder()
+	handler.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE glogger_entries_total counter\n",
+		`glogger_entries_total{level="error",logger=""} `,
+		`glogger_entries_suppressed_total{level="error",reason="sampled"} `,
+		`glogger_sink_bytes_total{sink="` + path + `"} `,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("content type = %q", ct)
+	}
+}
+
+type fixedCollector struct {
+	families []metrics.Family
+}
+
+func (c *fixedCollector) Collect() []metrics.Family { return c.families }
+
+func TestRegistryMergesCollectors(t *testing.T) {
+	registry := metrics.NewRegistry()
+	sample := metrics.Sample{Labels: []metrics.Label{{Name: "path", Value: "a\"b"}}, Value: 2}
+	family := metrics.Family{Name: "x_total", Type: "counter", Samples: []metrics.Sample{sample}}
+	registry.Register(&fixedCollector{[]metrics.Family{family}})
+	registry.Register(&fixedCollector{[]metrics.Family{family}})
+
+	var b strings.Builder
+	metrics.WriteText(&b, registry.Gather())
+	if want := "# TYPE x_total counter\nx_total{path=\"a\\\"b\"} 4\n"; b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
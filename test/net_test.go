@@ -0,0 +1,182 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/sink"
+)
+
+func TestGLoggerUDPSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: filepath.Join(t.TempDir(), "app.log"),
+		Sinks:      []glogger.SinkConfig{{Path: "udp://" + conn.LocalAddr().String(), MinLevel: "error"}},
+	})
+	log.Error("shipped over udp")
+
+	buf := make([]byte, 64<<10)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(buf[:n]), "shipped over udp") {
+		t.Errorf("datagram = %q", buf[:n])
+	}
+}
+
+func TestGLoggerUDPSinkAsync(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: filepath.Join(t.TempDir(), "app.log"),
+		Sinks:      []glogger.SinkConfig{{Path: "udp://" + conn.LocalAddr().String(), MinLevel: "error"}},
+		Async:      &sink.AsyncConfig{FlushInterval: time.Hour},
+	})
+	for i := 0; i < 3; i++ {
+		log.Error("queued")
+	}
+	log.Close(context.Background())
+
+	// Every entry of the flushed batch arrives in a datagram of its own.
+	buf := make([]byte, 64<<10)
+	for i := 0; i < 3; i++ {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := strings.Count(string(buf[:n]), "queued"); c != 1 {
+			t.Errorf("datagram holds %d entries: %q", c, buf[:n])
+		}
+	}
+}
+
+func TestNetWriterDropsOversizedDatagram(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w, err := sink.NewNetWriter(sink.NetConfig{Network: "udp", Address: conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Write([]byte(strings.Repeat("x", 70<<10) + "\n"))
+	w.Write([]byte("small\n"))
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Dropped() != 1 {
+		t.Errorf("dropped %d messages, want 1", w.Dropped())
+	}
+	buf := make([]byte, 64<<10)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "small\n" {
+		t.Errorf("datagram = %q", buf[:n])
+	}
+}
+
+func TestNetWriterDoesNotBlockWrites(t *testing.T) {
+	// The collector accepts the connection but never reads, so the socket
+	// buffers fill up and sends start to block.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	w, err := sink.NewNetWriter(sink.NetConfig{
+		Network:      "tcp",
+		Address:      ln.Addr().String(),
+		WriteTimeout: 2 * time.Second,
+		BufferSize:   64 << 20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	msg := []byte(strings.Repeat("x", 64<<10) + "\n")
+	start := time.Now()
+	for i := 0; i < 512; i++ {
+		w.Write(msg)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("writes took %v behind a stalled collector", d)
+	}
+}
+
+func TestNetWriterReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	w, err := sink.NewNetWriter(sink.NetConfig{
+		Network:       "tcp",
+		Address:       addr,
+		OctetCounting: true,
+		Backoff:       sink.Backoff{Initial: time.Millisecond, Max: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The collector is down: the message stays in the buffer.
+	w.Write([]byte("while down\n"))
+	if err := w.Sync(); err == nil {
+		t.Fatal("expected Sync to report buffered messages")
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	time.Sleep(5 * time.Millisecond)
+	w.Write([]byte("after restart\n"))
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	w.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	data, _ := bufio.NewReader(conn).ReadString('!')
+	if data != "10 while down13 after restart" {
+		t.Errorf("received %q", data)
+	}
+}
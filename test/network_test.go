@@ -0,0 +1,127 @@
+package test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/handler"
+	"github.com/MSLibs/glogger/utils"
+)
+
+func TestChooseIP(t *testing.T) {
+	addr := func(s string) net.Addr {
+		ip, n, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &net.IPNet{IP: ip, Mask: n.Mask}
+	}
+	dual := []net.Addr{addr("fe80::1/64"), addr("2001:db8::10/64"), addr("192.0.2.10/24")}
+	v6only := []net.Addr{addr("fe80::1/64"), addr("2001:db8::10/64")}
+	for _, tt := range []struct {
+		name   string
+		addrs  []net.Addr
+		prefer utils.IPPreference
+		want   string
+	}{
+		{"dual prefer v4", dual, utils.PreferIPv4, "192.0.2.10"},
+		{"dual prefer v6", dual, utils.PreferIPv6, "2001:db8::10"},
+		{"v6-only host", v6only, utils.PreferIPv4, "2001:db8::10"},
+		{"v6-only host, v4 only", v6only, utils.IPv4Only, ""},
+		{"dual, v6 only", dual, utils.IPv6Only, "2001:db8::10"},
+	} {
+		got, err := utils.ChooseIP(tt.addrs, utils.IPOptions{Prefer: tt.prefer})
+		if got != tt.want || (err != nil) != (tt.want == "") {
+			t.Errorf("%s: ChooseIP = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestSplitHostZone(t *testing.T) {
+	for _, tt := range []struct {
+		in, host, ip string
+	}{
+		{"192.0.2.1:8080", "192.0.2.1", "192.0.2.1"},
+		{"192.0.2.1", "192.0.2.1", "192.0.2.1"},
+		{"[::1]:8080", "::1", "::1"},
+		{"::1", "::1", "::1"},
+		{"[2001:db8::17]", "2001:db8::17", "2001:db8::17"},
+		{"[fe80::1%eth0]:80", "fe80::1%eth0", "fe80::1"},
+		{"unknown", "unknown", "<nil>"},
+	} {
+		host, ip := utils.SplitHostZone(tt.in)
+		if host != tt.host || ip.String() != tt.ip {
+			t.Errorf("SplitHostZone(%q) = %q, %v, want %q, %s", tt.in, host, ip, tt.host, tt.ip)
+		}
+	}
+}
+
+func TestLogRequestHandlerIPv6(t *testing.T) {
+	mw := handler.LogRequestHandlerWith(handler.RequestLogConfig{
+		TrustedProxies: []string{"2001:db8:1::/48", "fe80::/10"},
+	})
+	for _, tt := range []struct {
+		remote string
+		header http.Header
+		want   string
+	}{
+		{"[::1]:8080", nil, "::1"},
+		{"192.0.2.5:8080", nil, "192.0.2.5"},
+		{"[fe80::1%eth0]:8080", http.Header{"X-Forwarded-For": {"2001:db8:2::9"}}, "2001:db8:2::9"},
+		{"[2001:db8:1::1]:443", http.Header{"Forwarded": {`for="[2001:db8:2::17]:4711", for="[2001:db8:1::2]"`}}, "2001:db8:2::17"},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tt.remote
+		for k, v := range tt.header {
+			req.Header[k] = v
+		}
+		if got := serveContext(t, mw, req).Value(glogger.SourceIP); got != tt.want {
+			t.Errorf("%s: sourceip = %v, want %s", tt.remote, got, tt.want)
+		}
+	}
+}
+
+func TestChooseIPCIDR(t *testing.T) {
+	addrs := []net.Addr{
+		&net.IPNet{IP: net.ParseIP("172.17.0.2"), Mask: net.CIDRMask(16, 32)},
+		&net.IPNet{IP: net.ParseIP("10.4.0.7"), Mask: net.CIDRMask(16, 32)},
+	}
+	got, err := utils.ChooseIP(addrs, utils.IPOptions{CIDRs: []string{"10.0.0.0/8"}})
+	if err != nil || got != "10.4.0.7" {
+		t.Errorf("ChooseIP = %q, %v, want 10.4.0.7", got, err)
+	}
+}
+
+func TestServerIPCache(t *testing.T) {
+	os.Setenv("TEST_POD_IP", "10.9.8.7")
+	defer os.Unsetenv("TEST_POD_IP")
+
+	c := utils.NewServerIPCache(utils.ServerIPConfig{Env: "TEST_POD_IP"})
+	if got := c.Get(); got != "10.9.8.7" {
+		t.Errorf("from env = %q", got)
+	}
+	os.Setenv("TEST_POD_IP", "10.9.8.8")
+	if got := c.Get(); got != "10.9.8.7" {
+		t.Errorf("cached value changed without a refresh: %q", got)
+	}
+	if got, err := c.Refresh(); err != nil || got != "10.9.8.8" {
+		t.Errorf("Refresh = %q, %v", got, err)
+	}
+
+	c = utils.NewServerIPCache(utils.ServerIPConfig{Env: "-", Options: utils.IPOptions{CIDRs: []string{"198.18.255.0/24"}}})
+	if got, err := c.Refresh(); got != "" || err == nil {
+		t.Errorf("Refresh with no matching address = %q, %v", got, err)
+	}
+
+	mw := handler.LogRequestHandlerWith(handler.RequestLogConfig{
+		ServerIP: utils.NewServerIPCache(utils.ServerIPConfig{Override: "192.0.2.80"}),
+	})
+	ctx := serveContext(t, mw, httptest.NewRequest("GET", "/", nil))
+	if got := ctx.Value(glogger.ServerIP); got != "192.0.2.80" {
+		t.Errorf("serverip = %v", got)
+	}
+}
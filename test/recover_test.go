@@ -0,0 +1,71 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/handler"
+)
+
+func explode() {
+	panic("boom")
+}
+
+func TestRecoverHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{OutputPath: path})
+	defer log.Close(context.Background())
+
+	h := handler.LogRequestHandler(handler.RecoverHandlerWith(handler.RecoverConfig{
+		Body:        `{"error":"internal"}`,
+		ContentType: "application/json",
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		explode()
+	})))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/orders?token=abc", nil))
+	log.Sync()
+
+	if rec.Code != http.StatusInternalServerError || rec.Body.String() != `{"error":"internal"}` {
+		t.Errorf("response = %d %q", rec.Code, rec.Body.String())
+	}
+	data := readFile(t, path)
+	for _, want := range []string{
+		"level?=error#", handler.PanicMessage, "panic?=boom#", "POST /orders?token=*** HTTP/1.1",
+		"url?=/orders?token=***#", "trace?=", "test.explode",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("%q missing:\n%s", want, data)
+		}
+	}
+	if n := strings.Count(data, "test.explode"); n != 1 {
+		t.Errorf("stack logged %d times:\n%s", n, data)
+	}
+	if strings.Contains(data, "abc") {
+		t.Errorf("token leaked:\n%s", data)
+	}
+}
+
+func TestRecoverHandlerRepanicsAbort(t *testing.T) {
+	h := handler.RecoverHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", p)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	t.Errorf("ErrAbortHandler was swallowed")
+}
+
+func TestRecoverHandlerHijack(t *testing.T) {
+	if body := hijackServer(t, handler.RecoverHandler); body != "hijacked" {
+		t.Errorf("body = %q, want the hijacked response", body)
+	}
+}
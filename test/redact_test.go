@@ -0,0 +1,123 @@
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/redact"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestGLoggerRedact(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	rawPath := filepath.Join(dir, "raw.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: path,
+		Redact: &redact.Config{
+			Keys: []redact.KeyRule{
+				{Pattern: "*password*", Strategy: redact.Drop},
+				{Pattern: "token", Strategy: redact.Hash},
+			},
+			Detectors: redact.DefaultDetectors,
+		},
+		Sinks: []glogger.SinkConfig{{Path: rawPath, Redact: &redact.Config{}}},
+	})
+	ctx := context.WithValue(context.Background(), glogger.UserFlag, "18512343365")
+	log.WithInfo(&ctx, "mail sent to jane.doe@example.com",
+		zap.String("db_password", "hunter2"),
+		zap.String("token", "abc"),
+		zap.String("card", "4111 1111 1111 1111"),
+		zap.String("order", "1234567890123"),
+		zap.String("auth", "Bearer eyJhbGciOi.J9"),
+	)
+	log.Close(context.Background())
+
+	data := readFile(t, path)
+	for _, leaked := range []string{"18512343365", "hunter2", "db_password", "jane.doe@", "4111 1111 1111 1111", "eyJhbGciOi", "=abc#"} {
+		if strings.Contains(data, leaked) {
+			t.Errorf("%q leaked: %s", leaked, data)
+		}
+	}
+	for _, want := range []string{"userflag?=185****3365#", "jan*************.com", "token?=sha256:", "411************1111", "order?=1234567890123#", "auth?=***#"} {
+		if !strings.Contains(data, want) {
+			t.Errorf("%q missing: %s", want, data)
+		}
+	}
+	if !strings.Contains(readFile(t, rawPath), "hunter2") {
+		t.Errorf("sink override did not disable redaction")
+	}
+}
+
+type credentials struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+func (c credentials) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("user", c.User)
+	enc.AddString("password", c.Password)
+	return nil
+}
+
+func TestRedactNestedFields(t *testing.T) {
+	r, err := redact.New(redact.Config{
+		Keys:      []redact.KeyRule{{Pattern: "password"}},
+		Detectors: redact.DefaultDetectors,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "nested.log")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	log := zap.New(redact.NewCore(zapcore.NewCore(enc, f, zapcore.DebugLevel), r))
+	log.Info("login",
+		zap.Any("req", map[string]string{"password": "hunter2", "phone": "18512343365"}),
+		zap.Any("list", []credentials{{User: "bob", Password: "s3cret"}}),
+		zap.Object("creds", credentials{User: "ann", Password: "letmein"}),
+		zap.Any("count", 3),
+	)
+	log.Sync()
+
+	data := readFile(t, path)
+	for _, leaked := range []string{"hunter2", "18512343365", "s3cret", "letmein"} {
+		if strings.Contains(data, leaked) {
+			t.Errorf("%q leaked: %s", leaked, data)
+		}
+	}
+	for _, want := range []string{`"phone":"185****3365"`, `"user":"bob"`, `"user":"ann"`, `"count":3`} {
+		if !strings.Contains(data, want) {
+			t.Errorf("%q missing: %s", want, data)
+		}
+	}
+}
+
+func TestRedactStrategies(t *testing.T) {
+	for _, tt := range []struct {
+		strategy redact.Strategy
+		in, want string
+	}{
+		{redact.Mask, "secret", "***"},
+		{redact.Partial, "18512343365", "185****3365"},
+		{redact.Partial, "short", "***"},
+		{redact.Drop, "gone", ""},
+	} {
+		if got := redact.Apply(tt.strategy, tt.in); got != tt.want {
+			t.Errorf("Apply(%s, %q) = %q, want %q", tt.strategy, tt.in, got, tt.want)
+		}
+	}
+	if _, err := redact.New(redact.Config{Detectors: []redact.Detector{{Name: "ssn"}}}); err == nil {
+		t.Errorf("unknown detector accepted")
+	}
+}
@@ -0,0 +1,43 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MSLibs/glogger"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestGLoggerReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{OutputPath: path})
+	log.Info("before rotation")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	log.Info("during rotation")
+	if err := log.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	log.Info("after rotation")
+
+	rotated, current := readFile(t, path+".1"), readFile(t, path)
+	if !strings.Contains(rotated, "before rotation") || !strings.Contains(rotated, "during rotation") {
+		t.Errorf("rotated file incomplete: %s", rotated)
+	}
+	if !strings.Contains(current, "after rotation") || strings.Contains(current, "before rotation") {
+		t.Errorf("reopened file unexpected: %s", current)
+	}
+}
@@ -0,0 +1,42 @@
+//go:build !windows
+// +build !windows
+
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/MSLibs/glogger"
+)
+
+func TestGLoggerReopenOnSignal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath:     path,
+		ReopenOnSignal: true,
+		ReopenSignal:   syscall.SIGUSR1,
+	})
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("file was not reopened after the signal")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	log.Info("after signal")
+	if current := readFile(t, path); !strings.Contains(current, "after signal") {
+		t.Errorf("reopened file unexpected: %s", current)
+	}
+}
@@ -0,0 +1,179 @@
+package test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/sink"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type lokiServer struct {
+	mu      sync.Mutex
+	auth    string
+	streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+}
+
+func (s *lokiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auth = r.Header.Get("Authorization")
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	var push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.NewDecoder(body).Decode(&push); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.streams = append(s.streams, push.Streams...)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestGLoggerShipperLoki(t *testing.T) {
+	loki := &lokiServer{}
+	server := httptest.NewServer(loki)
+	defer server.Close()
+
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: filepath.Join(t.TempDir(), "app.log"),
+		Shipper: &sink.ShipperConfig{
+			URL:           server.URL,
+			Format:        "loki",
+			Labels:        map[string]string{"app": "orders"},
+			LabelKeys:     []string{"platformId"},
+			Gzip:          true,
+			BearerToken:   "t0ken",
+			BatchInterval: time.Hour,
+		},
+	})
+	for _, platform := range []string{"web", "ios", "web"} {
+		ctx := context.WithValue(context.Background(), glogger.PlatformID, platform)
+		glogger.WithContext(&ctx).Info("order placed")
+	}
+	log.Close(context.Background())
+
+	if loki.auth != "Bearer t0ken" {
+		t.Errorf("Authorization = %q", loki.auth)
+	}
+	lines := map[string]int{}
+	for _, st := range loki.streams {
+		if st.Stream["app"] != "orders" {
+			t.Errorf("static label missing: %v", st.Stream)
+		}
+		if p, ok := st.Stream["platformId"]; ok {
+			lines[p] += len(st.Values)
+		}
+	}
+	if lines["web"] != 2 || lines["ios"] != 1 {
+		t.Errorf("lines per platform = %v, streams = %+v", lines, loki.streams)
+	}
+}
+
+func TestGLoggerShipperLokiDefaultLabel(t *testing.T) {
+	loki := &lokiServer{}
+	server := httptest.NewServer(loki)
+	defer server.Close()
+
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: filepath.Join(t.TempDir(), "app.log"),
+		Service:    "orders",
+		Shipper: &sink.ShipperConfig{
+			URL:           server.URL,
+			Format:        "loki",
+			BatchInterval: time.Hour,
+		},
+	})
+	log.Info("order placed")
+	log.Close(context.Background())
+
+	if len(loki.streams) == 0 {
+		t.Fatal("nothing shipped")
+	}
+	for _, st := range loki.streams {
+		if st.Stream["service"] != "orders" {
+			t.Errorf("stream labels = %v, want service=orders", st.Stream)
+		}
+	}
+}
+
+func TestShipperNDJSONRetry(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		fails = 2
+		user  string
+		docs  []map[string]interface{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fails > 0 {
+			fails--
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		user, _, _ = r.BasicAuth()
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var doc map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+				t.Errorf("bad line %q: %v", scanner.Text(), err)
+			}
+			docs = append(docs, doc)
+		}
+	}))
+	defer server.Close()
+
+	s, err := sink.NewShipper(sink.ShipperConfig{
+		URL:           server.URL,
+		Username:      "shipper",
+		Password:      "pw",
+		BatchInterval: time.Hour,
+		Backoff:       sink.Backoff{Initial: time.Millisecond},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	log := zap.New(sink.NewShipperCore(enc, s, zapcore.InfoLevel))
+	log.Info("first")
+	log.Warn("second")
+	s.Flush()
+	s.Close()
+
+	if user != "shipper" {
+		t.Errorf("basic auth user = %q", user)
+	}
+	if len(docs) != 2 || docs[0]["msg"] != "first" || docs[1]["msg"] != "second" {
+		t.Errorf("docs = %v", docs)
+	}
+}
@@ -0,0 +1,38 @@
+package test
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/sink"
+)
+
+func TestGLoggerClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "close.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: path,
+		Async:      &sink.AsyncConfig{FlushInterval: time.Hour},
+	})
+	log.Info("last words")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := log.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "last words") {
+		t.Errorf("entry lost on Close: %s", data)
+	}
+	if err := log.Close(ctx); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
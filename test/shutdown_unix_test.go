@@ -0,0 +1,56 @@
+//go:build !windows
+// +build !windows
+
+package test
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/sink"
+)
+
+func TestSignalHandlerKeepRunning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: path,
+		Async:      &sink.AsyncConfig{FlushInterval: time.Hour},
+	})
+	defer log.Close(context.Background())
+
+	app := make(chan os.Signal, 1)
+	signal.Notify(app, syscall.SIGUSR2)
+	defer signal.Stop(app)
+	glogger.InstallSignalHandlerWith(glogger.SignalConfig{
+		Signals:     []os.Signal{syscall.SIGUSR2},
+		KeepRunning: true,
+	})
+
+	log.Info("before signal")
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	select {
+	case <-app:
+	case <-time.After(time.Second):
+		t.Fatal("the application's own handler did not get the signal")
+	}
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(readFile(t, path), "before signal") {
+		if time.Now().After(deadline) {
+			t.Fatal("entry not flushed after the signal")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	log.Info("still logging")
+	log.Sync()
+	if !strings.Contains(readFile(t, path), "still logging") {
+		t.Errorf("logger closed by the signal handler")
+	}
+}
@@ -0,0 +1,41 @@
+package test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MSLibs/glogger"
+)
+
+func TestGLoggerErrorSink(t *testing.T) {
+	dir := t.TempDir()
+	errorLog := filepath.Join(dir, "error.log")
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: filepath.Join(dir, "app.log"),
+		Sinks: []glogger.SinkConfig{
+			{Path: errorLog, MinLevel: "error", Encoding: "json"},
+		},
+	})
+	log.Info("routine entry")
+	log.Error("broken entry")
+
+	data, err := ioutil.ReadFile(errorLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "routine entry") {
+		t.Errorf("info entry leaked into error sink: %s", data)
+	}
+	if !strings.Contains(string(data), `"msg":"broken entry"`) {
+		t.Errorf("error entry missing from error sink: %s", data)
+	}
+	all, err := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(all), "routine entry") || !strings.Contains(string(all), "broken entry") {
+		t.Errorf("main log incomplete: %s", all)
+	}
+}
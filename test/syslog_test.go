@@ -0,0 +1,139 @@
+//go:build !windows
+// +build !windows
+
+package test
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/encoder"
+	"github.com/MSLibs/glogger/core/sink"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestGLoggerSyslogSink(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: filepath.Join(dir, "app.log"),
+		Sinks:      []glogger.SinkConfig{{Path: "syslog://" + socket, MinLevel: "warn"}},
+		Syslog: &encoder.SyslogConfig{
+			Facility: "local0",
+			AppName:  "orders",
+			Hostname: "web-1",
+			SDParams: map[string]string{"env": "prod"},
+		},
+	})
+	ctx := context.WithValue(context.Background(), glogger.RequestID, "req-1")
+	ctx = context.WithValue(ctx, glogger.PlatformID, `p"1]`)
+	log.WithError(&ctx, "charge failed")
+
+	buf := make([]byte, 64<<10)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// local0 (16) * 8 + error (3) = 131
+	want := regexp.MustCompile(`^<131>1 \S+ web-1 orders \d+ - ` +
+		`\[glogger@32473 env="prod" requestId="req-1" platformId="p\\"1\\]" size="-1"\] ` +
+		`#caller\?=\S+#msg\?="charge failed"#`)
+	if !want.Match(buf[:n]) {
+		t.Errorf("syslog message = %q", buf[:n])
+	}
+}
+
+func TestGLoggerSyslogSinkAsync(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: filepath.Join(dir, "app.log"),
+		Sinks:      []glogger.SinkConfig{{Path: "syslog://" + socket, MinLevel: "warn"}},
+		Async:      &sink.AsyncConfig{FlushInterval: time.Hour},
+	})
+	for i := 0; i < 3; i++ {
+		log.Error("charge failed")
+	}
+	log.Close(context.Background())
+
+	// Each RFC 5424 message arrives in a datagram of its own.
+	buf := make([]byte, 64<<10)
+	for i := 0; i < 3; i++ {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := regexp.MustCompile(`<\d+>1 `).FindAll(buf[:n], -1); len(c) != 1 {
+			t.Errorf("datagram holds %d messages: %q", len(c), buf[:n])
+		}
+	}
+}
+
+func TestGLoggerSyslogOutputPath(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: "syslog+udp://" + conn.LocalAddr().String(),
+		Syslog:     &encoder.SyslogConfig{AppName: "orders", Hostname: "web-1"},
+	})
+	log.Info("started")
+	log.Close(context.Background())
+
+	buf := make([]byte, 64<<10)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// user (1) * 8 + info (6) = 14
+	if want := regexp.MustCompile(`^<14>1 \S+ web-1 orders \d+ - `); !want.Match(buf[:n]) {
+		t.Errorf("syslog message = %q", buf[:n])
+	}
+}
+
+func TestSyslogEncoderRFC3164(t *testing.T) {
+	enc, err := encoder.NewSyslogEncoder(encoder.SyslogConfig{RFC3164: true, AppName: "orders", Hostname: "web-1"},
+		encoder.NewKVEncoder(zapEncoderConfig()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := enc.EncodeEntry(infoEntry("hello"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := regexp.MustCompile(`^<14>\w{3} [ \d]\d \d\d:\d\d:\d\d web-1 orders\[\d+\]: #msg\?="hello"#\n$`); !want.Match(buf.Bytes()) {
+		t.Errorf("rfc3164 message = %q", buf.Bytes())
+	}
+}
+
+func zapEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{MessageKey: "msg", LineEnding: "\n"}
+}
+
+func infoEntry(msg string) zapcore.Entry {
+	return zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: msg}
+}
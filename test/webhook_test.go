@@ -0,0 +1,162 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MSLibs/glogger"
+	"github.com/MSLibs/glogger/core/sink"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type alertServer struct {
+	mu      sync.Mutex
+	fail    int
+	status  int
+	batches [][]map[string]interface{}
+	token   string
+}
+
+func (s *alertServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = r.Header.Get("X-Token")
+	if s.fail > 0 {
+		s.fail--
+		if s.status == 0 {
+			s.status = http.StatusServiceUnavailable
+		}
+		w.WriteHeader(s.status)
+		return
+	}
+	var batch []map[string]interface{}
+	body, _ := ioutil.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.batches = append(s.batches, batch)
+}
+
+func (s *alertServer) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var msgs []string
+	for _, b := range s.batches {
+		for _, e := range b {
+			msgs = append(msgs, e["msg"].(string))
+		}
+	}
+	return msgs
+}
+
+func TestGLoggerWebhook(t *testing.T) {
+	alerts := &alertServer{fail: 2}
+	server := httptest.NewServer(alerts)
+	defer server.Close()
+
+	log := glogger.CreateLog(glogger.GLoggerConfig{
+		OutputPath: filepath.Join(t.TempDir(), "app.log"),
+		Webhook: &sink.WebhookConfig{
+			URL:           server.URL,
+			Headers:       map[string]string{"X-Token": "secret"},
+			BatchInterval: time.Hour,
+			Backoff:       sink.Backoff{Initial: time.Millisecond},
+		},
+	})
+	log.Info("not an alert")
+	for i := 0; i < 3; i++ {
+		log.Error("payment gateway down")
+	}
+	log.Error("disk full")
+	log.Close(context.Background())
+
+	msgs := alerts.messages()
+	if len(msgs) != 2 || msgs[0] != "payment gateway down" || msgs[1] != "disk full" {
+		t.Errorf("alerts = %v", msgs)
+	}
+	if alerts.token != "secret" {
+		t.Errorf("header not sent, got %q", alerts.token)
+	}
+}
+
+func TestWebhookSpool(t *testing.T) {
+	alerts := &alertServer{fail: 1000}
+	server := httptest.NewServer(alerts)
+	defer server.Close()
+	spoolDir := t.TempDir()
+
+	w, err := sink.NewWebhook(sink.WebhookConfig{
+		URL:           server.URL,
+		BatchInterval: time.Hour,
+		SpoolDir:      spoolDir,
+		Backoff:       sink.Backoff{Initial: time.Millisecond, Retries: 1},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := newWebhookLogger(w)
+	log.Error("while down")
+	w.Flush()
+
+	spool, _ := sink.NewSpool(spoolDir, 0)
+	if spool.Len() != 1 {
+		t.Fatalf("spooled %d batches, want 1", spool.Len())
+	}
+
+	alerts.mu.Lock()
+	alerts.fail = 0
+	alerts.mu.Unlock()
+	log.Error("after recovery")
+	w.Close()
+
+	msgs := alerts.messages()
+	if len(msgs) != 2 || msgs[0] != "after recovery" || msgs[1] != "while down" {
+		t.Errorf("alerts = %v", msgs)
+	}
+	if spool.Len() != 0 {
+		t.Errorf("spool not drained")
+	}
+}
+
+func TestWebhookSpoolSkipsRejected(t *testing.T) {
+	alerts := &alertServer{fail: 1000, status: http.StatusBadRequest}
+	server := httptest.NewServer(alerts)
+	defer server.Close()
+	spoolDir := t.TempDir()
+
+	w, err := sink.NewWebhook(sink.WebhookConfig{
+		URL:           server.URL,
+		BatchInterval: time.Hour,
+		SpoolDir:      spoolDir,
+		Backoff:       sink.Backoff{Initial: time.Millisecond, Retries: 1},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := newWebhookLogger(w)
+	for i := 0; i < 2; i++ {
+		log.Error("rejected")
+		w.Flush()
+	}
+	w.Close()
+
+	spool, _ := sink.NewSpool(spoolDir, 0)
+	if spool.Len() != 0 {
+		t.Errorf("spooled %d rejected batches, want 0", spool.Len())
+	}
+}
+
+func newWebhookLogger(w *sink.Webhook) *zap.Logger {
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	return zap.New(sink.NewWebhookCore(enc, w, zapcore.ErrorLevel))
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+)
+
+var (
+	containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+	// mountinfo lists the container's own hostname and resolv.conf files,
+	// which live under .../containers/<id>/.
+	mountinfoIDPattern = regexp.MustCompile(`/containers/([0-9a-f]{64})/`)
+)
+
+// ContainerID returns the ID of the container the process runs in, read
+// from /proc/self/cgroup or, on cgroup v2 hosts, /proc/self/mountinfo. It
+// returns "" outside a container or when neither file says.
+func ContainerID() string {
+	if f, err := os.Open("/proc/self/cgroup"); err == nil {
+		id := ContainerIDFrom(f)
+		f.Close()
+		if id != "" {
+			return id
+		}
+	}
+	if f, err := os.Open("/proc/self/mountinfo"); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if m := mountinfoIDPattern.FindStringSubmatch(scanner.Text()); m != nil {
+				return m[1]
+			}
+		}
+	}
+	return ""
+}
+
+// ContainerIDFrom finds a container ID, a 64 hex digit string such as the
+// ones in /docker/<id> or cri-containerd-<id>.scope, in a cgroup listing.
+func ContainerIDFrom(cgroup io.Reader) string {
+	scanner := bufio.NewScanner(cgroup)
+	for scanner.Scan() {
+		if ids := containerIDPattern.FindAllString(scanner.Text(), -1); len(ids) > 0 {
+			return ids[len(ids)-1]
+		}
+	}
+	return ""
+}
@@ -5,39 +5,153 @@ import (
 	"net"
 )
 
+// IPPreference selects the address family ExternalIPWith returns.
+type IPPreference int
+
+const (
+	// PreferIPv4 returns an IPv4 address when there is one, else an IPv6 one.
+	PreferIPv4 IPPreference = iota
+	// PreferIPv6 returns an IPv6 address when there is one, else an IPv4 one.
+	PreferIPv6
+	// IPv4Only never returns an IPv6 address.
+	IPv4Only
+	// IPv6Only never returns an IPv4 address.
+	IPv6Only
+)
+
+// IPOptions configures ExternalIPWith.
+type IPOptions struct {
+	Prefer IPPreference
+	// Interfaces, when set, restricts the search to the interfaces with
+	// these names, such as eth0.
+	Interfaces []string
+	// CIDRs, when set, only accepts addresses inside these networks.
+	CIDRs []string
+}
+
+var errNoAddress = errors.New("are you connected to the network?")
+
+// ExternalIP returns the first IPv4 address of an interface that is up and
+// not a loopback, or an IPv6 one on hosts without IPv4.
 func ExternalIP() (string, error) {
+	return ExternalIPWith(IPOptions{})
+}
+
+// ExternalIPWith is ExternalIP with the interfaces and address family chosen
+// by opts.
+func ExternalIPWith(opts IPOptions) (string, error) {
 	ifaces, err := net.Interfaces()
 	if err != nil {
 		return "", err
 	}
+	var addrs []net.Addr
 	for _, iface := range ifaces {
+		if len(opts.Interfaces) > 0 && !contains(opts.Interfaces, iface.Name) {
+			continue
+		}
 		if iface.Flags&net.FlagUp == 0 {
 			continue // interface down
 		}
 		if iface.Flags&net.FlagLoopback != 0 {
 			continue // loopback interface
 		}
-		addrs, err := iface.Addrs()
+		ifAddrs, err := iface.Addrs()
 		if err != nil {
 			return "", err
 		}
-		for _, addr := range addrs {
-			var ip net.IP
-			switch v := addr.(type) {
-			case *net.IPNet:
-				ip = v.IP
-			case *net.IPAddr:
-				ip = v.IP
-			}
-			if ip == nil || ip.IsLoopback() {
-				continue
-			}
-			ip = ip.To4()
-			if ip == nil {
-				continue // not an ipv4 address
+		addrs = append(addrs, ifAddrs...)
+	}
+	return ChooseIP(addrs, opts)
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ChooseIP picks the address ExternalIPWith would return out of addrs.
+// Loopback, link-local and unspecified addresses are skipped.
+func ChooseIP(addrs []net.Addr, opts IPOptions) (string, error) {
+	nets := make([]*net.IPNet, 0, len(opts.CIDRs))
+	for _, c := range opts.CIDRs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return "", err
+		}
+		nets = append(nets, n)
+	}
+	var v4, v6 net.IP
+	for _, addr := range addrs {
+		var ip net.IP
+		switch v := addr.(type) {
+		case *net.IPNet:
+			ip = v.IP
+		case *net.IPAddr:
+			ip = v.IP
+		}
+		if ip == nil || ip.IsLoopback() || ip.IsUnspecified() ||
+			ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+			continue
+		}
+		if len(nets) > 0 && !inAny(nets, ip) {
+			continue
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			if v4 == nil {
+				v4 = ip4
 			}
+		} else if v6 == nil {
+			v6 = ip
+		}
+	}
+	var order []net.IP
+	switch opts.Prefer {
+	case PreferIPv6:
+		order = []net.IP{v6, v4}
+	case IPv4Only:
+		order = []net.IP{v4}
+	case IPv6Only:
+		order = []net.IP{v6}
+	default:
+		order = []net.IP{v4, v6}
+	}
+	for _, ip := range order {
+		if ip != nil {
 			return ip.String(), nil
 		}
 	}
-	return "", errors.New("are you connected to the network?")
+	return "", errNoAddress
+}
+
+func inAny(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitHostZone returns the address in s without its port or brackets, as
+// in [fe80::1%eth0]:8080, and parses the IP without its zone. ip is nil if
+// host is not an IP address.
+func SplitHostZone(s string) (host string, ip net.IP) {
+	host = s
+	if h, _, err := net.SplitHostPort(s); err == nil {
+		host = h
+	} else if len(s) > 1 && s[0] == '[' && s[len(s)-1] == ']' {
+		host = s[1 : len(s)-1]
+	}
+	addr := host
+	for i := 0; i < len(addr); i++ {
+		if addr[i] == '%' {
+			addr = addr[:i]
+			break
+		}
+	}
+	return host, net.ParseIP(addr)
 }
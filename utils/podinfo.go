@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultPodInfoDir is where downward-API volumes are usually mounted.
+const DefaultPodInfoDir = "/etc/podinfo"
+
+// PodInfo describes the Kubernetes pod the process runs in. Fields are empty
+// when unknown.
+type PodInfo struct {
+	Namespace string
+	Pod       string
+	Node      string
+	Labels    map[string]string
+}
+
+// ReadPodInfo reads the POD_NAMESPACE, POD_NAME and NODE_NAME environment
+// variables, then fills the gaps from the namespace, name, nodename and
+// labels files of a downward-API volume mounted at dir. Missing sources are
+// skipped, so outside Kubernetes the result is simply empty.
+func ReadPodInfo(dir string) PodInfo {
+	if dir == "" {
+		dir = DefaultPodInfoDir
+	}
+	info := PodInfo{
+		Namespace: os.Getenv("POD_NAMESPACE"),
+		Pod:       os.Getenv("POD_NAME"),
+		Node:      os.Getenv("NODE_NAME"),
+	}
+	if info.Namespace == "" {
+		info.Namespace = readPodInfoFile(dir, "namespace")
+	}
+	if info.Pod == "" {
+		info.Pod = readPodInfoFile(dir, "name")
+	}
+	if info.Node == "" {
+		info.Node = readPodInfoFile(dir, "nodename")
+	}
+	if f, err := os.Open(filepath.Join(dir, "labels")); err == nil {
+		info.Labels = parseDownwardMap(f)
+		f.Close()
+	}
+	return info
+}
+
+func readPodInfoFile(dir, name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// parseDownwardMap parses the key="value" lines of a downward-API labels or
+// annotations file.
+func parseDownwardMap(r io.Reader) map[string]string {
+	m := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			continue
+		}
+		val := line[eq+1:]
+		if unquoted, err := strconv.Unquote(val); err == nil {
+			val = unquoted
+		}
+		m[line[:eq]] = val
+	}
+	return m
+}
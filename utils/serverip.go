@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// ServerIPConfig configures a ServerIPCache.
+type ServerIPConfig struct {
+	// Override, when set, is used instead of looking at the interfaces.
+	Override string
+	// Env names an environment variable that overrides the interfaces when
+	// set, "POD_IP" by default (the Kubernetes downward API convention). Use
+	// "-" to ignore the environment.
+	Env string
+	// RefreshInterval, when positive, re-resolves the address in the
+	// background once it is older than that.
+	RefreshInterval time.Duration
+	// Options select the interface and address family.
+	Options IPOptions
+}
+
+// ServerIPCache resolves the server address once and hands it out without
+// enumerating the interfaces again.
+type ServerIPCache struct {
+	cfg ServerIPConfig
+
+	mu         sync.RWMutex
+	ip         string
+	resolved   time.Time
+	refreshing bool
+}
+
+// NewServerIPCache resolves the address right away.
+func NewServerIPCache(cfg ServerIPConfig) *ServerIPCache {
+	if cfg.Env == "" {
+		cfg.Env = "POD_IP"
+	}
+	c := &ServerIPCache{cfg: cfg}
+	c.Refresh()
+	return c
+}
+
+var (
+	defaultServerIP     *ServerIPCache
+	defaultServerIPOnce sync.Once
+)
+
+// DefaultServerIP returns the cache shared by callers without their own
+// configuration.
+func DefaultServerIP() *ServerIPCache {
+	defaultServerIPOnce.Do(func() {
+		defaultServerIP = NewServerIPCache(ServerIPConfig{})
+	})
+	return defaultServerIP
+}
+
+// Get returns the cached address, "" if none could be resolved, and starts
+// a background refresh when the address is stale.
+func (c *ServerIPCache) Get() string {
+	c.mu.RLock()
+	ip, stale := c.ip, c.stale()
+	c.mu.RUnlock()
+	if stale {
+		c.mu.Lock()
+		if !c.refreshing && c.stale() {
+			c.refreshing = true
+			go c.Refresh()
+		}
+		c.mu.Unlock()
+	}
+	return ip
+}
+
+func (c *ServerIPCache) stale() bool {
+	return c.cfg.RefreshInterval > 0 && time.Since(c.resolved) >= c.cfg.RefreshInterval
+}
+
+// Refresh resolves the address now. On failure the previous address is
+// kept.
+func (c *ServerIPCache) Refresh() (string, error) {
+	ip, err := c.resolve()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshing = false
+	c.resolved = time.Now()
+	if err != nil {
+		return c.ip, err
+	}
+	c.ip = ip
+	return ip, nil
+}
+
+func (c *ServerIPCache) resolve() (string, error) {
+	if c.cfg.Override != "" {
+		return c.cfg.Override, nil
+	}
+	if c.cfg.Env != "-" {
+		if ip := os.Getenv(c.cfg.Env); ip != "" {
+			return ip, nil
+		}
+	}
+	return ExternalIPWith(c.cfg.Options)
+}